@@ -0,0 +1,91 @@
+package gostore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Backup writes a consistent copy of the database file to w while the
+// store keeps serving reads and writes. It returns the number of bytes
+// written.
+func (s *Store) Backup(w io.Writer) (n int64, err error) {
+	err = s.view(func(tx *bolt.Tx) error {
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// BackupOp returns a ScheduledOp writing a Backup to dir, in a file named
+// after the database file and the time of the backup, such as
+// store.db.20060102-150405.000.bak. The file only appears once complete.
+func BackupOp(dir string) ScheduledOp {
+	return func(s *Store) error {
+		name := fmt.Sprintf("%s.%s.bak", filepath.Base(s.path), time.Now().Format("20060102-150405.000"))
+		f, err := os.CreateTemp(dir, name+".tmp-*")
+		if err != nil {
+			return err
+		}
+		tmp := f.Name()
+		defer os.Remove(tmp)
+		if _, err := s.Backup(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(tmp, _fileMode); err != nil {
+			return err
+		}
+		return os.Rename(tmp, filepath.Join(dir, name))
+	}
+}
+
+// RestoreFrom writes a backup read from r to path. The backup is checked
+// before it replaces the file at path, which must not be open.
+func RestoreFrom(r io.Reader, path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".restore-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := checkFile(tmp); err != nil {
+		return fmt.Errorf("bad backup: %w", err)
+	}
+	if err := os.Chmod(tmp, _fileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// checkFile verifies the database file at path
+func checkFile(path string) error {
+	db, err := bolt.Open(path, _fileMode, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(checkTx)
+}
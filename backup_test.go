@@ -0,0 +1,52 @@
+package gostore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	var buf bytes.Buffer
+	n, err := s.Backup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes got %d", buf.Len(), n)
+	}
+
+	restored, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(restored)
+	if err := RestoreFrom(bytes.NewReader([]byte("not a database")), restored); err == nil {
+		t.Error("expected error")
+	}
+	if err := RestoreFrom(&buf, restored); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := Open(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if v, err := s2.Get([]byte("test"), []byte("key")); err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+}
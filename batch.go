@@ -0,0 +1,217 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _defaultMaxTxSize = 32 << 20
+
+// ErrTxTooBig is returned by PutBatchAtomic when the records do not fit in
+// one transaction.
+var ErrTxTooBig = errors.New("transaction too big")
+
+// WithMaxTxSize sets how many bytes of keys and values a batch writes per
+// transaction. Large transactions grow the memory map and hold the write
+// lock for long; PutBatch and ImportDir split their work to stay below it.
+func WithMaxTxSize(size int) Option {
+	return func(o *option) error {
+		o.maxTxSize = size
+		return nil
+	}
+}
+
+func (s *Store) maxTxSize() int {
+	if size := s.opt.Load().maxTxSize; size > 0 {
+		return size
+	}
+	return _defaultMaxTxSize
+}
+
+// PutBatch inserts records into a namespace, splitting them across as many
+// transactions as needed to stay below the maximum transaction size. On
+// error the records of the transactions already committed stay written;
+// the returned count says how many.
+func (s *Store) PutBatch(namespace string, kvs []KV) (int, error) {
+	bufs, err := s.encodeBatch(kvs)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.putSplit([]byte(namespace), bufs)
+	if err != nil {
+		err = fmt.Errorf("failed to put batch: %w", err)
+	}
+	return n, err
+}
+
+// PutBatchAtomic inserts records into a namespace in a single transaction.
+// It fails with ErrTxTooBig instead of splitting them.
+func (s *Store) PutBatchAtomic(namespace string, kvs []KV) error {
+	bufs, err := s.encodeBatch(kvs)
+	if err != nil {
+		return err
+	}
+	size := 0
+	for _, kv := range bufs {
+		size += len(kv.Key) + len(kv.Value)
+	}
+	if size > s.maxTxSize() {
+		return fmt.Errorf("%w: %d bytes", ErrTxTooBig, size)
+	}
+	if err := s.putBatch([]byte(namespace), bufs); err != nil {
+		return fmt.Errorf("failed to put batch: %w", err)
+	}
+	return nil
+}
+
+// putSplit writes encoded records in as many transactions as needed to stay
+// below the maximum transaction size and returns the number written.
+func (s *Store) putSplit(namespace []byte, bufs []KV) (int, error) {
+	return s.splitBatch(bufs, func(batch []KV) error {
+		return s.putBatch(namespace, batch)
+	})
+}
+
+// splitBatch calls write with consecutive runs of bufs, each below the
+// maximum transaction size, and returns the number of records written.
+func (s *Store) splitBatch(bufs []KV, write func(batch []KV) error) (int, error) {
+	total := 0
+	limit := s.maxTxSize()
+	for len(bufs) > 0 {
+		n, size := 0, 0
+		for n < len(bufs) && (n == 0 || size+len(bufs[n].Key)+len(bufs[n].Value) <= limit) {
+			size += len(bufs[n].Key) + len(bufs[n].Value)
+			n++
+		}
+		if err := write(bufs[:n]); err != nil {
+			return total, err
+		}
+		total += n
+		bufs = bufs[n:]
+	}
+	return total, nil
+}
+
+// Entry is a record written by PutMulti.
+type Entry struct {
+	Namespace []byte
+	Key       []byte
+	Value     []byte
+	// TTL is the time to live in seconds, zero for none.
+	TTL int64
+}
+
+// PutMulti inserts records into any namespaces in a single transaction, so
+// either all of them are written or none is. It fails with ErrTxTooBig if
+// they exceed the maximum transaction size.
+func (s *Store) PutMulti(entries []Entry) error {
+	bufs := make([][]byte, len(entries))
+	size := 0
+	for i, e := range entries {
+		if e.Value == nil {
+			return ErrBadValue
+		}
+		buf, err := newValueT(e.Value, e.TTL).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		bufs[i] = buf
+		size += len(e.Key) + len(buf)
+	}
+	if size > s.maxTxSize() {
+		return fmt.Errorf("%w: %d bytes", ErrTxTooBig, size)
+	}
+	err := s.update(func(tx *bolt.Tx) error {
+		for i, e := range entries {
+			if err := s.put(tx, e.Namespace, s.diskKey(e.Key), bufs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to put entries: %w", err)
+	}
+	return err
+}
+
+func (s *Store) putBatch(namespace []byte, bufs []KV) error {
+	return s.update(func(tx *bolt.Tx) error {
+		for _, kv := range bufs {
+			if err := s.put(tx, namespace, kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// encodeBatch encodes the values of kvs as records without expiry, under
+// their stored keys
+func (s *Store) encodeBatch(kvs []KV) ([]KV, error) {
+	bufs := make([]KV, len(kvs))
+	for i, kv := range kvs {
+		if kv.Value == nil {
+			return nil, ErrBadValue
+		}
+		buf, err := newValueT(kv.Value, 0).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		bufs[i] = KV{Key: s.diskKey(kv.Key), Value: buf}
+	}
+	return bufs, nil
+}
+
+// DeleteBatch deletes keys from a namespace in a single transaction.
+// Missing keys are ignored.
+func (s *Store) DeleteBatch(namespace []byte, keys ...[]byte) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		for _, key := range s.diskKeys(keys) {
+			if err := s.delete(tx, namespace, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to delete batch: %w", err)
+	}
+	return err
+}
+
+// DeletePrefix deletes every record of a namespace whose key starts with
+// prefix, expired ones included, in a single transaction and returns how
+// many it deleted. With WithHashedKeys the prefix must be empty, as stored
+// keys are hashes; other prefixes fail with ErrHashedKeys.
+func (s *Store) DeletePrefix(namespace, prefix []byte) (n int, err error) {
+	if len(prefix) > 0 && s.opt.Load().hashedKeys {
+		return 0, ErrHashedKeys
+	}
+	err = s.update(func(tx *bolt.Tx) error {
+		n = 0
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+		var keys [][]byte
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, bytes.Clone(k))
+		}
+		for _, key := range keys {
+			if err := s.delete(tx, namespace, key); err != nil {
+				return err
+			}
+		}
+		n = len(keys)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete prefix %s: %w", prefix, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,128 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestPutBatch(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxTxSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var kvs []KV
+	for i := 0; i < 50; i++ {
+		kvs = append(kvs, KV{Key: []byte(fmt.Sprintf("k%02d", i)), Value: []byte("value")})
+	}
+	n, err := s.PutBatch("ns", kvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(kvs) {
+		t.Errorf("expected %d got %d", len(kvs), n)
+	}
+	for _, kv := range kvs {
+		if v, err := s.Get([]byte("ns"), kv.Key); err != nil || string(v) != "value" {
+			t.Errorf("expected value got %s, %v", v, err)
+		}
+	}
+
+	if err := s.PutBatchAtomic("ns", kvs); !errors.Is(err, ErrTxTooBig) {
+		t.Errorf("expected error %s, got %v", ErrTxTooBig, err)
+	}
+	if err := s.PutBatchAtomic("ns", kvs[:2]); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.PutBatch("ns", []KV{{Key: []byte("nil")}}); err != ErrBadValue {
+		t.Errorf("expected error %s, got %v", ErrBadValue, err)
+	}
+}
+
+func TestPutMulti(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithImmutableNamespace("frozen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.PutMulti([]Entry{
+		{Namespace: []byte("objects"), Key: []byte("1"), Value: []byte("alice")},
+		{Namespace: []byte("index"), Key: []byte("alice"), Value: []byte("1"), TTL: 3600},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get([]byte("index"), []byte("alice")); err != nil || string(v) != "1" {
+		t.Errorf("expected 1 got %s, %v", v, err)
+	}
+
+	if err := s.Put("frozen", []byte("k"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	err = s.PutMulti([]Entry{
+		{Namespace: []byte("objects"), Key: []byte("2"), Value: []byte("bob")},
+		{Namespace: []byte("frozen"), Key: []byte("k"), Value: []byte("v2")},
+	})
+	if !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %v", ErrImmutable, err)
+	}
+	if _, err := s.Get([]byte("objects"), []byte("2")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestDeleteBatch(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("ns")
+	for _, key := range []string{"user:1", "user:2", "user:3", "group:1"} {
+		if err := s.Put(string(ns), []byte(key), []byte("v")); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.DeleteBatch(ns, []byte("user:1"), []byte("missing")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(ns, []byte("user:1")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	n, err := s.DeletePrefix(ns, []byte("user:"))
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 deleted got %d", n)
+	}
+	kvs, err := s.Scan(ns, nil, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 1 || string(kvs[0].Key) != "group:1" {
+		t.Errorf("expected only group:1 left got %v", kvs)
+	}
+	if n, err := s.DeletePrefix([]byte("none"), nil); err != nil || n != 0 {
+		t.Errorf("expected 0, nil got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,49 @@
+package gostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+)
+
+const _bootstrapTimeout = 10 * time.Minute
+
+// WithBootstrapSnapshot restores the store from the snapshot or backup
+// served at url when the database file does not exist yet, so a fresh node
+// starts with the data before serving. A 404 starts an empty store; any
+// other failure fails Open.
+func WithBootstrapSnapshot(url string) Option {
+	return func(o *option) error {
+		o.bootstrapURL = url
+		return nil
+	}
+}
+
+// bootstrap downloads the snapshot at url to path unless path exists
+func bootstrap(url, path string) error {
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), _bootstrapTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("snapshot %s: %s", url, resp.Status)
+	}
+	return RestoreFrom(resp.Body, path)
+}
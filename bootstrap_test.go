@@ -0,0 +1,69 @@
+package gostore
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestBootstrapSnapshot(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	var buf bytes.Buffer
+	if _, err := s.Backup(&buf); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	fresh := path + ".fresh"
+	defer os.RemoveAll(fresh)
+	s2, err := Open(fresh, WithBootstrapSnapshot(srv.URL+"/latest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s2.Get([]byte("test"), []byte("key")); err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+	if err := s2.Put("test", []byte("key"), []byte("local")); err != nil {
+		t.Error(err)
+	}
+	s2.Close()
+
+	// an existing file is kept
+	s2, err = Open(fresh, WithBootstrapSnapshot(srv.URL+"/latest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s2.Get([]byte("test"), []byte("key")); err != nil || string(v) != "local" {
+		t.Errorf("expected local got %s, %v", v, err)
+	}
+	s2.Close()
+
+	empty := path + ".empty"
+	defer os.RemoveAll(empty)
+	s3, err := Open(empty, WithBootstrapSnapshot(srv.URL+"/missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3.Close()
+}
@@ -0,0 +1,82 @@
+package gostore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Memoize while the loader of a key is
+// failing, see WithCircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// WithCircuitBreaker stops calling the loader of a memoized key after
+// threshold consecutive failures. For coolDown, Memoize returns
+// ErrCircuitOpen right away; the next call after it tries the loader again
+// and, if it fails, opens the circuit for another coolDown.
+func WithCircuitBreaker(threshold int, coolDown time.Duration) Option {
+	return func(o *option) error {
+		o.breakerThreshold = threshold
+		o.breakerCoolDown = coolDown
+		return nil
+	}
+}
+
+// breakers tracks the failures of memoize loaders by key. Keys without
+// failures have no entry.
+type breakers struct {
+	mu    sync.Mutex
+	state map[string]*breakerState
+}
+
+type breakerState struct {
+	failures int
+	openedAt time.Time
+}
+
+// allow reports whether the loader of key may be called
+func (b *breakers) allow(key string, threshold int, coolDown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.state[key]
+	return st == nil || st.failures < threshold || time.Since(st.openedAt) >= coolDown
+}
+
+// record updates the state of key after its loader ran
+func (b *breakers) record(key string, threshold int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		delete(b.state, key)
+		return
+	}
+	if b.state == nil {
+		b.state = make(map[string]*breakerState)
+	}
+	st := b.state[key]
+	if st == nil {
+		st = &breakerState{}
+		b.state[key] = st
+	}
+	st.failures++
+	if st.failures >= threshold {
+		st.openedAt = time.Now()
+	}
+}
+
+// guard wraps a memoize loader with the circuit breaker of key, if enabled
+func (s *Store) guard(key string, f func() (any, error)) func() (any, error) {
+	opt := s.opt.Load()
+	threshold, coolDown := opt.breakerThreshold, opt.breakerCoolDown
+	if threshold <= 0 {
+		return f
+	}
+	return func() (any, error) {
+		if !s.breakers.allow(key, threshold, coolDown) {
+			return nil, ErrCircuitOpen
+		}
+		data, err := f()
+		s.breakers.record(key, threshold, err)
+		return data, err
+	}
+}
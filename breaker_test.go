@@ -0,0 +1,52 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithCircuitBreaker(2, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	errUpstream := errors.New("upstream down")
+	calls := 0
+	failing := func() (any, error) {
+		calls++
+		return nil, errUpstream
+	}
+	var v T1
+	for i := 0; i < 2; i++ {
+		if err := s.Memoize("test", &v, failing); err != errUpstream {
+			t.Errorf("expected error %s, got %v", errUpstream, err)
+		}
+	}
+	if err := s.Memoize("test", &v, failing); err != ErrCircuitOpen {
+		t.Errorf("expected error %s, got %v", ErrCircuitOpen, err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	// other keys are not affected
+	if err := s.Memoize("other", &v, func() (any, error) { return &T1{Name: "ok"}, nil }); err != nil {
+		t.Error(err)
+	}
+
+	time.Sleep(time.Second)
+	if err := s.Memoize("test", &v, func() (any, error) { return &T1{Name: "back"}, nil }); err != nil {
+		t.Error(err)
+	}
+	if v.Name != "back" {
+		t.Errorf("expected back got %s", v.Name)
+	}
+}
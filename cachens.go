@@ -0,0 +1,70 @@
+package gostore
+
+import (
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// WithCacheNamespaces makes Get and Put of the given namespaces read through
+// and populate the caches, as Load and Update do for the default namespace.
+func WithCacheNamespaces(namespaces ...string) Option {
+	return func(o *option) error {
+		if o.cacheNamespaces == nil {
+			o.cacheNamespaces = make(map[string]struct{})
+		}
+		for _, ns := range namespaces {
+			o.cacheNamespaces[ns] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// cacheKey returns the key of a record in the caches. Records of the
+// default namespace use their plain key, as Load and Update do; the others
+// are prefixed by their namespace.
+func cacheKey(namespace, key []byte) string {
+	if string(namespace) == _defaultBucket {
+		return string(key)
+	}
+	return "\x00" + string(namespace) + "\x00" + string(key)
+}
+
+// readThrough returns the cache key of a record if Get and Put cache its
+// namespace
+func (s *Store) readThrough(namespace, key []byte) (string, bool) {
+	if s.lru == nil && s.large == nil {
+		return "", false
+	}
+	if _, ok := s.opt.Load().cacheNamespaces[string(namespace)]; !ok {
+		return "", false
+	}
+	return cacheKey(namespace, key), true
+}
+
+// invalidate drops a record from the caches once tx commits
+func (s *Store) invalidate(tx *bolt.Tx, namespace, key []byte) {
+	if s.lru == nil && s.large == nil {
+		return
+	}
+	if _, ok := s.opt.Load().cacheNamespaces[string(namespace)]; !ok && string(namespace) != _defaultBucket {
+		return
+	}
+	ck := cacheKey(namespace, key)
+	tx.OnCommit(func() { s.cacheDelete(ck) })
+}
+
+// cachePurge drops all records of a namespace from the caches
+func (s *Store) cachePurge(namespace []byte) {
+	match := func(k string) bool { return !strings.HasPrefix(k, "\x00") }
+	if string(namespace) != _defaultBucket {
+		prefix := cacheKey(namespace, nil)
+		match = func(k string) bool { return strings.HasPrefix(k, prefix) }
+	}
+	if s.lru != nil {
+		s.lru.DeleteFunc(match)
+	}
+	if s.large != nil {
+		s.large.DeleteFunc(match)
+	}
+}
@@ -0,0 +1,65 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCacheNamespaces(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(10), WithCacheNamespaces("hot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	hot, cold := []byte("hot"), []byte("cold")
+	if err := s.Put("hot", []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("cold", []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.lru.Get(cacheKey(hot, []byte("a"))); !ok {
+		t.Error("expected put to populate the cache")
+	}
+	if _, ok := s.lru.Get(cacheKey(cold, []byte("a"))); ok {
+		t.Error("expected cold namespace not to be cached")
+	}
+
+	v, err := s.Get(hot, []byte("a"))
+	if err != nil || string(v) != "1" {
+		t.Errorf("expected 1 got %s, %v", v, err)
+	}
+	v[0] = 'x'
+	if v, _ := s.Get(hot, []byte("a")); string(v) != "1" {
+		t.Errorf("expected cached value to be unchanged, got %s", v)
+	}
+
+	if err := s.Delete("hot", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(hot, []byte("a")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+
+	if err := s.Tx(func(tx *StoreTx) error { return tx.Put(hot, []byte("b"), []byte("2")) }); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(hot, []byte("b")); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.lru.Get(cacheKey(hot, []byte("b"))); !ok {
+		t.Error("expected get to populate the cache")
+	}
+	if err := s.DeleteNamespace("hot"); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(hot, []byte("b")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
@@ -0,0 +1,116 @@
+package gostore
+
+import (
+	"bytes"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CAS replaces the value of key with new if its current value is expected,
+// in a single transaction, and reports whether it did. A nil expected
+// matches a missing or expired key; a nil new deletes the key.
+func (s *Store) CAS(namespace, key, expected, new []byte) (swapped bool, err error) {
+	dk := s.diskKey(key)
+	err = s.update(func(tx *bolt.Tx) error {
+		swapped = false
+		current, ok, err := s.current(tx, namespace, dk)
+		if err != nil {
+			return err
+		}
+		if ok != (expected != nil) || !bytes.Equal(current, expected) {
+			return nil
+		}
+		if new == nil {
+			if err := s.delete(tx, namespace, dk); err != nil {
+				return err
+			}
+		} else {
+			buf, err := newValueT(new, 0).MarshalBinary()
+			if err != nil {
+				return err
+			}
+			if err := s.put(tx, namespace, dk, buf); err != nil {
+				return err
+			}
+		}
+		swapped = true
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to swap key %s: %w", key, err)
+	}
+	return swapped, err
+}
+
+// current returns the live value of key within tx
+func (s *Store) current(tx *bolt.Tx, namespace, key []byte) ([]byte, bool, error) {
+	bucket := tx.Bucket(namespace)
+	if bucket == nil {
+		return nil, false, nil
+	}
+	v := bucket.Get(key)
+	if v == nil {
+		return nil, false, nil
+	}
+	var value valueT
+	if err := value.UnmarshalBinary(v); err != nil {
+		return nil, false, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if value.isExpired() {
+		return nil, false, nil
+	}
+	return value.Value, true, nil
+}
+
+// PutNX inserts key only if it is missing or expired, in a single
+// transaction, and reports whether it did. ttl is in seconds, zero for none.
+func (s *Store) PutNX(namespace, key, value []byte, ttl int64) (stored bool, err error) {
+	dk := s.diskKey(key)
+	err = s.update(func(tx *bolt.Tx) error {
+		stored = false
+		if _, ok, err := s.current(tx, namespace, dk); err != nil || ok {
+			return err
+		}
+		buf, err := newValueT(value, ttl).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := s.put(tx, namespace, dk, buf); err != nil {
+			return err
+		}
+		stored = true
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+	return stored, err
+}
+
+// GetOrSet returns the live value of key, or stores value if the key is
+// missing or expired, in a single transaction. loaded reports whether the
+// value was already there. ttl is in seconds, zero for none.
+func (s *Store) GetOrSet(namespace, key, value []byte, ttl int64) (actual []byte, loaded bool, err error) {
+	dk := s.diskKey(key)
+	err = s.update(func(tx *bolt.Tx) error {
+		current, ok, err := s.current(tx, namespace, dk)
+		if err != nil {
+			return err
+		}
+		if ok {
+			actual, loaded = current, true
+			return nil
+		}
+		buf, err := newValueT(value, ttl).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		actual, loaded = value, false
+		return s.put(tx, namespace, dk, buf)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+	return actual, loaded, nil
+}
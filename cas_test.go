@@ -0,0 +1,160 @@
+package gostore
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCAS(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("ns"), []byte("key")
+	for _, tc := range []struct {
+		expected, new []byte
+		swapped       bool
+	}{
+		{[]byte("v1"), []byte("v2"), false},
+		{nil, []byte("v1"), true},
+		{nil, []byte("v2"), false},
+		{[]byte("v2"), []byte("v3"), false},
+		{[]byte("v1"), []byte("v2"), true},
+		{[]byte("v2"), nil, true},
+	} {
+		swapped, err := s.CAS(ns, key, tc.expected, tc.new)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if swapped != tc.swapped {
+			t.Errorf("CAS(%s, %s): expected %v got %v", tc.expected, tc.new, tc.swapped, swapped)
+		}
+	}
+	if _, err := s.Get(ns, key); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestCASConcurrent(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("ns"), []byte("key")
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		won int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			swapped, err := s.CAS(ns, key, nil, []byte("mine"))
+			if err != nil {
+				t.Error(err)
+			}
+			if swapped {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Errorf("expected 1 swap, got %d", won)
+	}
+}
+
+func TestPutNX(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("locks"), []byte("job")
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		won int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stored, err := s.PutNX(ns, key, []byte("owner"), 1)
+			if err != nil {
+				t.Error(err)
+			}
+			if stored {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Errorf("expected 1 winner got %d", won)
+	}
+	time.Sleep(2 * time.Second)
+	if stored, err := s.PutNX(ns, key, []byte("next"), 0); err != nil || !stored {
+		t.Errorf("expected expired key to be replaced, got %v, %v", stored, err)
+	}
+	if v, err := s.Get(ns, key); err != nil || string(v) != "next" {
+		t.Errorf("expected next got %s, %v", v, err)
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("ns"), []byte("key")
+	for _, tc := range []struct {
+		value, actual []byte
+		loaded        bool
+	}{
+		{[]byte("v1"), []byte("v1"), false},
+		{[]byte("v2"), []byte("v1"), true},
+	} {
+		actual, loaded, err := s.GetOrSet(ns, key, tc.value, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(tc.actual) || loaded != tc.loaded {
+			t.Errorf("GetOrSet(%s): expected %s, %v got %s, %v", tc.value, tc.actual, tc.loaded, actual, loaded)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_cdcBucket           = _internalPrefix + "cdc"
+	_defaultCDCBatchSize = 100
+	_defaultCDCInterval  = time.Second
+)
+
+// ErrChangesTrimmed is reported when the change log dropped changes that a
+// CDC stream had not delivered yet.
+var ErrChangesTrimmed = errors.New("changes trimmed before delivery")
+
+// Sink receives committed changes from a CDC stream. When Write fails, the
+// same changes are delivered again, so delivery is at-least-once.
+type Sink interface {
+	Write(ctx context.Context, changes []Change) error
+}
+
+// CDCOptions configures a CDC stream.
+type CDCOptions struct {
+	// BatchSize is the maximum number of changes per Write.
+	BatchSize int
+	// Interval is how often the change log is polled.
+	Interval time.Duration
+	// OnError is called with sink errors and gaps in the change log.
+	OnError func(error)
+}
+
+// CDC streams the change log to a sink, checkpointing delivered changes
+// under its name so a restarted stream resumes where it stopped.
+type CDC struct {
+	s    *Store
+	name string
+	sink Sink
+	opts CDCOptions
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartCDC starts streaming changes to sink from the checkpoint of name.
+// It needs the store to be opened with WithChangeLog. The stream stops, and
+// the context of a running Write is cancelled, when the store is closed.
+func (s *Store) StartCDC(name string, sink Sink, opts CDCOptions) (*CDC, error) {
+	if !s.opt.Load().changeLog {
+		return nil, ErrNoChangeLog
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = _defaultCDCBatchSize
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = _defaultCDCInterval
+	}
+	s.bgMu.Lock()
+	defer s.bgMu.Unlock()
+	select {
+	case <-s.done:
+		return nil, ErrClosed
+	default:
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &CDC{s: s, name: name, sink: sink, opts: opts, cancel: cancel, done: make(chan struct{})}
+	s.wg.Add(1)
+	go c.run(ctx)
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-c.done:
+		}
+	}()
+	return c, nil
+}
+
+// Checkpoint returns the sequence number of the last delivered change
+func (c *CDC) Checkpoint() (uint64, error) {
+	var seq uint64
+	err := c.s.view(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(_cdcBucket)); bucket != nil {
+			if v := bucket.Get([]byte(c.name)); len(v) == 8 {
+				seq = binary.BigEndian.Uint64(v)
+			}
+		}
+		return nil
+	})
+	return seq, err
+}
+
+// Close stops the stream
+func (c *CDC) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+func (c *CDC) run(ctx context.Context) {
+	defer c.s.wg.Done()
+	defer close(c.done)
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		for c.deliver(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends one batch and reports whether there may be more.
+func (c *CDC) deliver(ctx context.Context) bool {
+	seq, err := c.Checkpoint()
+	if err != nil {
+		c.report(err)
+		return false
+	}
+	changes, err := c.s.ChangesSince(seq, c.opts.BatchSize)
+	if err != nil {
+		c.report(err)
+		return false
+	}
+	if len(changes) == 0 {
+		return false
+	}
+	if changes[0].Seq != seq+1 {
+		c.report(fmt.Errorf("%w: %d to %d", ErrChangesTrimmed, seq+1, changes[0].Seq-1))
+	}
+	if err := c.sink.Write(ctx, changes); err != nil {
+		c.report(err)
+		return false
+	}
+	last := changes[len(changes)-1].Seq
+	if err := c.s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(_cdcBucket))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(c.name), seqKey(last))
+	}); err != nil {
+		c.report(err)
+		return false
+	}
+	return len(changes) == c.opts.BatchSize
+}
+
+func (c *CDC) report(err error) {
+	if c.opts.OnError != nil {
+		c.opts.OnError(err)
+	}
+}
+
+// String returns the name of the operation
+func (op ChangeOp) String() string {
+	switch op {
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	case OpDeleteNamespace:
+		return "delete_namespace"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (op ChangeOp) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (op *ChangeOp) UnmarshalText(text []byte) error {
+	for _, o := range []ChangeOp{OpPut, OpDelete, OpDeleteNamespace} {
+		if o.String() == string(text) {
+			*op = o
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown change op %q", text)
+}
+
+// WriterSink writes changes as JSON lines to w.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing JSON lines to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink
+func (s *WriterSink) Write(_ context.Context, changes []Change) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, change := range changes {
+		if err := enc.Encode(change); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// FileSink appends changes as JSON lines to a file, syncing after each
+// batch.
+type FileSink struct {
+	WriterSink
+	f *os.File
+}
+
+// NewFileSink opens or creates the file at path for appending
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, _fileMode)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{WriterSink: WriterSink{w: f}, f: f}, nil
+}
+
+// Write implements Sink
+func (s *FileSink) Write(ctx context.Context, changes []Change) error {
+	if err := s.WriterSink.Write(ctx, changes); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// Message is a keyed message, as written to a log-based broker.
+type Message struct {
+	Key   []byte
+	Value []byte
+}
+
+// MessageWriter is implemented by broker producers such as Kafka writers.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...Message) error
+}
+
+// MessageSink publishes each change as a message keyed by namespace and
+// key, so changes to one key stay ordered within a partition.
+type MessageSink struct {
+	w MessageWriter
+}
+
+// NewMessageSink returns a sink publishing to w
+func NewMessageSink(w MessageWriter) *MessageSink {
+	return &MessageSink{w: w}
+}
+
+// Write implements Sink
+func (s *MessageSink) Write(ctx context.Context, changes []Change) error {
+	msgs := make([]Message, 0, len(changes))
+	for _, change := range changes {
+		value, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 0, len(change.Namespace)+1+len(change.Key))
+		key = append(append(append(key, change.Namespace...), '/'), change.Key...)
+		msgs = append(msgs, Message{Key: key, Value: value})
+	}
+	return s.w.WriteMessages(ctx, msgs...)
+}
+
+// WebhookSink POSTs each batch of changes as a JSON array.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting to url. A nil client uses a client
+// timing out after 10 seconds.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = &http.Client{Timeout: _webhookTimeout}
+	}
+	return &WebhookSink{url: url, client: client}
+}
+
+// Write implements Sink
+func (s *WebhookSink) Write(ctx context.Context, changes []Change) error {
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
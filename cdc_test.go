@@ -0,0 +1,234 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMessageWriter struct {
+	mu   sync.Mutex
+	msgs []Message
+	fail bool
+}
+
+func (w *testMessageWriter) WriteMessages(_ context.Context, msgs ...Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fail {
+		return errors.New("broker down")
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *testMessageWriter) len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.msgs)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCDC(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithChangeLog(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	w := &testMessageWriter{fail: true}
+	var (
+		mu     sync.Mutex
+		failed int
+	)
+	opts := CDCOptions{BatchSize: 2, Interval: 10 * time.Millisecond, OnError: func(error) {
+		mu.Lock()
+		failed++
+		mu.Unlock()
+	}}
+	c, err := s.StartCDC("broker", NewMessageSink(w), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if err := s.Put("test", []byte(k), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return failed > 0
+	})
+	w.mu.Lock()
+	w.fail = false
+	w.mu.Unlock()
+	waitFor(t, func() bool { return w.len() == 3 })
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+	if string(w.msgs[0].Key) != "test/a" {
+		t.Errorf("expected key %s, got %s", "test/a", w.msgs[0].Key)
+	}
+	var change Change
+	if err := json.Unmarshal(w.msgs[2].Value, &change); err != nil {
+		t.Error(err)
+	}
+	if change.Op != OpPut || string(change.Key) != "c" || string(change.Value) != "value" {
+		t.Errorf("unexpected change %+v", change)
+	}
+
+	// a restarted stream resumes from its checkpoint
+	if err := s.Delete("test", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	c, err = s.StartCDC("broker", NewMessageSink(w), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	waitFor(t, func() bool { return w.len() == 4 })
+	seq, err := c.Checkpoint()
+	if err != nil {
+		t.Error(err)
+	}
+	if seq != 4 {
+		t.Errorf("expected checkpoint %d, got %d", 4, seq)
+	}
+}
+
+func TestCDCSinks(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithChangeLog(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var (
+		mu       sync.Mutex
+		received []Change
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var changes []Change
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, changes...)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	filePath, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(filePath)
+	fileSink, err := NewFileSink(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileSink.Close()
+
+	opts := CDCOptions{Interval: 10 * time.Millisecond}
+	webhook, err := s.StartCDC("webhook", NewWebhookSink(srv.URL, nil), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer webhook.Close()
+	file, err := s.StartCDC("file", fileSink, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	})
+	waitFor(t, func() bool {
+		data, _ := os.ReadFile(filePath)
+		return bytes.Count(data, []byte("\n")) == 1
+	})
+	if string(received[0].Key) != "key" {
+		t.Errorf("expected key %s, got %s", "key", received[0].Key)
+	}
+}
+
+// blockingSink blocks every Write until its context is done
+type blockingSink struct{ started chan struct{} }
+
+func (b blockingSink) Write(ctx context.Context, _ []Change) error {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCDCStoreClose(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithChangeLog(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	sink := blockingSink{started: make(chan struct{}, 1)}
+	if _, err := s.StartCDC("hung", sink, CDCOptions{Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	<-sink.started
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Close to cancel the hung sink")
+	}
+	if _, err := s.StartCDC("late", sink, CDCOptions{}); err != ErrClosed {
+		t.Errorf("expected error %s, got %v", ErrClosed, err)
+	}
+}
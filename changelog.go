@@ -0,0 +1,261 @@
+package gostore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _changesBucket = _internalPrefix + "changes"
+
+// ErrNoChangeLog is returned by the change log methods when the store was
+// opened without WithChangeLog.
+var ErrNoChangeLog = errors.New("change log is disabled")
+
+// ChangeOp is the kind of a mutation recorded in the change log.
+type ChangeOp uint8
+
+const (
+	// OpPut is a record being written.
+	OpPut ChangeOp = iota + 1
+	// OpDelete is a record being deleted, explicitly or because it expired.
+	OpDelete
+	// OpDeleteNamespace is a whole namespace being deleted. Key is empty.
+	OpDeleteNamespace
+)
+
+// Change is a committed mutation.
+type Change struct {
+	Seq       uint64    `json:"seq"`
+	Op        ChangeOp  `json:"op"`
+	Namespace []byte    `json:"namespace"`
+	Key       []byte    `json:"key,omitempty"`
+	Value     []byte    `json:"value,omitempty"`
+	Expire    time.Time `json:"expire"`
+}
+
+// WithChangeLog records every mutation under a monotonically increasing
+// sequence number, keeping at most maxEntries changes. Zero keeps them all.
+func WithChangeLog(maxEntries int) Option {
+	return func(o *option) error {
+		o.changeLog = true
+		o.changeLogSize = maxEntries
+		return nil
+	}
+}
+
+// PutSeq inserts a <key, value> record and returns the sequence number of
+// the change.
+func (s *Store) PutSeq(namespace string, key, value []byte) (seq uint64, err error) {
+	if !s.opt.Load().changeLog {
+		return 0, ErrNoChangeLog
+	}
+	err = s.update(func(tx *bolt.Tx) error {
+		buf, err := newValueT(value, 0).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := s.put(tx, []byte(namespace), s.diskKey(key), buf); err != nil {
+			return err
+		}
+		if bucket := tx.Bucket([]byte(_changesBucket)); bucket != nil {
+			seq = bucket.Sequence()
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+	return seq, err
+}
+
+// PutBatchSeq is PutBatch returning the sequence number of the change of
+// each record written, in the order of kvs. On error it returns the
+// sequences of the records already committed.
+func (s *Store) PutBatchSeq(namespace string, kvs []KV) ([]uint64, error) {
+	if !s.opt.Load().changeLog {
+		return nil, ErrNoChangeLog
+	}
+	bufs, err := s.encodeBatch(kvs)
+	if err != nil {
+		return nil, err
+	}
+	seqs := make([]uint64, 0, len(bufs))
+	_, err = s.splitBatch(bufs, func(batch []KV) error {
+		var written []uint64
+		if err := s.update(func(tx *bolt.Tx) error {
+			written = written[:0]
+			for _, kv := range batch {
+				if err := s.put(tx, []byte(namespace), kv.Key, kv.Value); err != nil {
+					return err
+				}
+				// the put is the last change it logs
+				written = append(written, tx.Bucket([]byte(_changesBucket)).Sequence())
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		seqs = append(seqs, written...)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to put batch: %w", err)
+	}
+	return seqs, err
+}
+
+// ChangesSince returns up to limit changes with a sequence number greater
+// than seq, oldest first. A limit of zero returns all of them.
+func (s *Store) ChangesSince(seq uint64, limit int) ([]Change, error) {
+	if !s.opt.Load().changeLog {
+		return nil, ErrNoChangeLog
+	}
+	if seq == math.MaxUint64 {
+		// no sequence follows it
+		return nil, nil
+	}
+	var changes []Change
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(_changesBucket))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek(seqKey(seq + 1)); k != nil; k, v = c.Next() {
+			change, err := decodeChange(v)
+			if err != nil {
+				return err
+			}
+			change.Seq = binary.BigEndian.Uint64(k)
+			changes = append(changes, change)
+			if limit > 0 && len(changes) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// changed records a mutation. It is appended to the change log and handed
+// to the listeners and watchers once the transaction commits. buf is the
+// encoded record of a put, or the deleted record if known.
+func (s *Store) changed(tx *bolt.Tx, op ChangeOp, namespace, key, buf []byte) error {
+	if isInternal(string(namespace)) {
+		return nil
+	}
+	if err := s.updateViews(tx, op, namespace, key, buf); err != nil {
+		return err
+	}
+	if err := s.watched(tx, op, namespace, key, buf); err != nil {
+		return err
+	}
+	logged := buf
+	if op != OpPut {
+		logged = nil
+	}
+	seq, err := s.logChange(tx, op, namespace, key, logged)
+	if err != nil {
+		return err
+	}
+	if len(s.listeners) == 0 && s.dual == nil {
+		return nil
+	}
+	change := Change{Seq: seq, Op: op, Namespace: bytes.Clone(namespace), Key: bytes.Clone(key)}
+	if op == OpPut {
+		var value valueT
+		if err := value.UnmarshalBinary(buf); err != nil {
+			return err
+		}
+		change.Value, change.Expire = value.Value, value.Expire
+	}
+	if s.dual != nil {
+		s.dual.record(tx, change)
+	}
+	if len(s.listeners) == 0 {
+		return nil
+	}
+	tx.OnCommit(func() {
+		for _, l := range s.listeners {
+			l(change)
+		}
+	})
+	return nil
+}
+
+// logChange appends a mutation to the change log, trimming the oldest
+// entries past the configured size, and returns its sequence number. buf
+// is the encoded record of a put.
+func (s *Store) logChange(tx *bolt.Tx, op ChangeOp, namespace, key, buf []byte) (uint64, error) {
+	opt := s.opt.Load()
+	if !opt.changeLog {
+		return 0, nil
+	}
+	bucket, err := tx.CreateBucketIfNotExists([]byte(_changesBucket))
+	if err != nil {
+		return 0, err
+	}
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	if err := bucket.Put(seqKey(seq), encodeChange(op, namespace, key, buf)); err != nil {
+		return 0, err
+	}
+	if opt.changeLogSize <= 0 {
+		return seq, nil
+	}
+	c := bucket.Cursor()
+	for k, _ := c.First(); k != nil && seq-binary.BigEndian.Uint64(k) >= uint64(opt.changeLogSize); k, _ = c.First() {
+		if err := c.Delete(); err != nil {
+			return 0, err
+		}
+	}
+	return seq, nil
+}
+
+func seqKey(seq uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, seq)
+	return k
+}
+
+func encodeChange(op ChangeOp, namespace, key, buf []byte) []byte {
+	b := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(namespace)+len(key)+len(buf))
+	b = append(b, byte(op))
+	b = binary.AppendUvarint(b, uint64(len(namespace)))
+	b = append(b, namespace...)
+	b = binary.AppendUvarint(b, uint64(len(key)))
+	b = append(b, key...)
+	return append(b, buf...)
+}
+
+func decodeChange(b []byte) (Change, error) {
+	var change Change
+	if len(b) == 0 {
+		return change, ErrCorrupted
+	}
+	change.Op, b = ChangeOp(b[0]), b[1:]
+	for _, field := range []*[]byte{&change.Namespace, &change.Key} {
+		n, size := binary.Uvarint(b)
+		if size <= 0 || uint64(len(b)-size) < n {
+			return change, ErrCorrupted
+		}
+		*field = append([]byte(nil), b[size:size+int(n)]...)
+		b = b[size+int(n):]
+	}
+	if change.Op == OpPut {
+		var value valueT
+		if err := value.UnmarshalBinary(b); err != nil {
+			return change, fmt.Errorf("%w: %w", ErrCorrupted, err)
+		}
+		change.Value, change.Expire = value.Value, value.Expire
+	}
+	return change, nil
+}
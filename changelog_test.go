@@ -0,0 +1,134 @@
+package gostore
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func TestChangeLog(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithChangeLog(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	seq1, err := s.PutSeq("test", []byte("a"), []byte("1"))
+	if err != nil {
+		t.Error(err)
+	}
+	seq2, err := s.PutSeq("test", []byte("b"), []byte("2"))
+	if err != nil {
+		t.Error(err)
+	}
+	if seq2 <= seq1 {
+		t.Errorf("expected sequence after %d, got %d", seq1, seq2)
+	}
+	if err := s.Delete("test", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	// deleting a missing key is not a change
+	if err := s.Delete("test", []byte("missing")); err != nil {
+		t.Error(err)
+	}
+
+	changes, err := s.ChangesSince(seq1, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected %d changes, got %d", 2, len(changes))
+	}
+	if changes[0].Seq != seq2 || changes[0].Op != OpPut || string(changes[0].Key) != "b" || string(changes[0].Value) != "2" {
+		t.Errorf("unexpected change %+v", changes[0])
+	}
+	if changes[1].Op != OpDelete || string(changes[1].Namespace) != "test" || string(changes[1].Key) != "a" {
+		t.Errorf("unexpected change %+v", changes[1])
+	}
+
+	// only the last 3 changes are kept
+	if err := s.Put("test", []byte("c"), []byte("3")); err != nil {
+		t.Error(err)
+	}
+	changes, err = s.ChangesSince(0, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(changes) != 3 || changes[0].Seq != seq2 {
+		t.Errorf("expected 3 changes from %d, got %+v", seq2, changes)
+	}
+	changes, err = s.ChangesSince(0, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("expected %d changes, got %d", 1, len(changes))
+	}
+	changes, err = s.ChangesSince(math.MaxUint64, 0)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v, %v", changes, err)
+	}
+}
+
+func TestPutBatchSeq(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	// one record per transaction
+	s, err := Open(path, WithChangeLog(0), WithMaxTxSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("test", []byte("a"), []byte("0")); err != nil {
+		t.Error(err)
+	}
+	seqs, err := s.PutBatchSeq("test", []KV{
+		{Key: []byte("b"), Value: []byte("1")},
+		{Key: []byte("c"), Value: []byte("2")},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(seqs) != 2 || seqs[0] != 2 || seqs[1] != 3 {
+		t.Fatalf("expected sequences [2 3], got %v", seqs)
+	}
+	changes, err := s.ChangesSince(seqs[0]-1, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(changes) != 2 || string(changes[0].Key) != "b" || string(changes[1].Key) != "c" {
+		t.Errorf("expected changes of b and c, got %+v", changes)
+	}
+}
+
+func TestChangeLogDisabled(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.PutSeq("test", []byte("a"), []byte("1")); err != ErrNoChangeLog {
+		t.Errorf("expected error %s, got %s", ErrNoChangeLog, err)
+	}
+	if _, err := s.PutBatchSeq("test", []KV{{Key: []byte("a"), Value: []byte("1")}}); err != ErrNoChangeLog {
+		t.Errorf("expected error %s, got %s", ErrNoChangeLog, err)
+	}
+	if _, err := s.ChangesSince(0, 0); err != ErrNoChangeLog {
+		t.Errorf("expected error %s, got %s", ErrNoChangeLog, err)
+	}
+}
@@ -0,0 +1,96 @@
+package gostore
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// _tempMaxAge is how old a temporary file that may still be in use must be
+// before CleanTemp removes it
+const _tempMaxAge = time.Hour
+
+// _boltMagic follows the page header of the meta pages of a bolt file
+const _boltMagic = 0xED0CDAED
+
+// CleanTemp removes the temporary files left in the directory of the
+// database file by interrupted calls to Compact, RestoreFrom and Snapshot
+// of this store, and returns their paths. Compaction files are always
+// removed, as the store holds the lock of its file; restore and snapshot
+// files only once they are an hour old, since another process may still be
+// writing them. Only bolt files named after the database file are
+// removed. Open runs it for stores that are not read-only; Schedule can
+// run it with CleanTempOp.
+func (s *Store) CleanTemp() ([]string, error) {
+	if s.opt.Load().readOnly {
+		return nil, bolt.ErrDatabaseReadOnly
+	}
+	dir, base := filepath.Dir(s.path), filepath.Base(s.path)
+	var (
+		removed []string
+		errs    []error
+	)
+	clean := func(pattern string, minAge time.Duration) {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		for _, path := range matches {
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() || time.Since(info.ModTime()) < minAge || !isBoltFile(path) {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+				continue
+			}
+			removed = append(removed, path)
+		}
+	}
+	// Compact holds dbMu exclusively while its file exists
+	s.dbMu.RLock()
+	clean(globEscape(base)+".compact-*", 0)
+	s.dbMu.RUnlock()
+	clean(globEscape(base)+".restore-*", _tempMaxAge)
+	clean(globEscape(base)+".snapshot-*", _tempMaxAge)
+	return removed, errors.Join(errs...)
+}
+
+// CleanTempOp is a ScheduledOp running CleanTemp.
+func CleanTempOp(s *Store) error {
+	_, err := s.CleanTemp()
+	return err
+}
+
+// isBoltFile reports whether the file at path starts with a bolt meta page
+func isBoltFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var b [20]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return false
+	}
+	return binary.LittleEndian.Uint32(b[16:]) == _boltMagic
+}
+
+// globEscape quotes the characters of name special to filepath.Match
+func globEscape(name string) string {
+	b := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '*', '?', '[', '\\':
+			b = append(b, '\\')
+		}
+		b = append(b, name[i])
+	}
+	return string(b)
+}
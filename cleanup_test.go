@@ -0,0 +1,59 @@
+package gostore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCleanTemp(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.db")
+	old := time.Now().Add(-2 * _tempMaxAge)
+	newBolt := func(name string, mtime time.Time) string {
+		p := filepath.Join(dir, name)
+		db, err := bolt.Open(p, _fileMode, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	compact := newBolt("store.db.compact-1", time.Now())
+	restore := newBolt("store.db.restore-1", old)
+	snapshot := newBolt("store.db.snapshot-1", old)
+	fresh := newBolt("store.db.snapshot-2", time.Now())
+	foreign := newBolt("backup.db.snapshot-1", old)
+	other := filepath.Join(dir, "notes.snapshot-1")
+	if err := os.WriteFile(other, []byte("not a database file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(other, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, p := range []string{compact, restore, snapshot} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected %s removed got %v", p, err)
+		}
+	}
+	for _, p := range []string{fresh, foreign, other, path} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s kept got %v", p, err)
+		}
+	}
+	if removed, err := s.CleanTemp(); err != nil || len(removed) != 0 {
+		t.Errorf("expected nothing left to remove got %v, %v", removed, err)
+	}
+}
@@ -0,0 +1,99 @@
+package gostore
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+// Codec encodes the values of LoadT and UpdateT.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values as JSON
+type JSONCodec struct{}
+
+// Marshal implements Codec
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// binaryCodec is the default codec. Values implementing
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler encode themselves,
+// others are encoded as JSON.
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return json.Marshal(v)
+}
+
+func (binaryCodec) Unmarshal(data []byte, v any) error {
+	if u, ok := v.(encoding.BinaryUnmarshaler); ok {
+		return u.UnmarshalBinary(data)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WithCodec sets the codec used by LoadT and UpdateT
+func WithCodec(c Codec) Option {
+	return func(o *option) error {
+		o.codec = c
+		return nil
+	}
+}
+
+func (s *Store) codec() Codec {
+	if c := s.opt.Load().codec; c != nil {
+		return c
+	}
+	return binaryCodec{}
+}
+
+// codecValue adapts a value to the encoding interfaces used by Update and
+// Load.
+type codecValue struct {
+	codec Codec
+	v     any
+}
+
+func (c codecValue) MarshalBinary() ([]byte, error) {
+	return c.codec.Marshal(c.v)
+}
+
+func (c codecValue) UnmarshalBinary(data []byte) error {
+	return c.codec.Unmarshal(data, c.v)
+}
+
+// LoadT reads the value of key, decoding it with the store codec. A
+// pointer type T gets a newly allocated value.
+func LoadT[T any](s *Store, key string) (T, error) {
+	var v T
+	var target any = &v
+	if rt := reflect.TypeOf(v); rt != nil && rt.Kind() == reflect.Pointer {
+		v = reflect.New(rt.Elem()).Interface().(T)
+		target = v
+	}
+	err := s.Load(key, codecValue{s.codec(), target})
+	return v, err
+}
+
+// UpdateT sets the value of key, encoding it with the store codec
+func UpdateT[T any](s *Store, key string, v T) error {
+	return UpdateWithTTLT(s, key, v, 0)
+}
+
+// UpdateWithTTLT sets the value of key with TTL, encoding it with the store
+// codec
+func UpdateWithTTLT[T any](s *Store, key string, v T, ttl int64) error {
+	return s.UpdateWithTTL(key, codecValue{s.codec(), v}, ttl)
+}
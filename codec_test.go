@@ -0,0 +1,72 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	type point struct{ X, Y int }
+	if err := UpdateT(s, "point", point{1, 2}); err != nil {
+		t.Error(err)
+	}
+	p, err := LoadT[point](s, "point")
+	if err != nil {
+		t.Error(err)
+	}
+	if p != (point{1, 2}) {
+		t.Errorf("expected {1 2} got %v", p)
+	}
+	if _, err := LoadT[point](s, "missing"); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+
+	// binary marshalers encode themselves
+	if err := UpdateT(s, "t1", &T1{Name: "test"}); err != nil {
+		t.Error(err)
+	}
+	var v T1
+	if err := s.Load("t1", &v); err != nil || v.Name != "test" {
+		t.Errorf("expected test got %s, %v", v.Name, err)
+	}
+	v1, err := LoadT[*T1](s, "t1")
+	if err != nil || v1.Name != "test" {
+		t.Errorf("expected test got %v, %v", v1, err)
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithCodec(JSONCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := UpdateT(s, "m", map[string]int{"a": 1}); err != nil {
+		t.Error(err)
+	}
+	v, err := s.Get([]byte(_defaultBucket), []byte("m"))
+	if err != nil || string(v) != `{"a":1}` {
+		t.Errorf("expected {\"a\":1} got %s, %v", v, err)
+	}
+	m, err := LoadT[map[string]int](s, "m")
+	if err != nil || m["a"] != 1 {
+		t.Errorf("expected 1 got %v, %v", m, err)
+	}
+}
@@ -0,0 +1,141 @@
+package gostore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _autoCompactInterval = time.Minute
+
+// WithAutoCompact compacts the database file whenever free pages make up
+// more than threshold, a fraction between 0 and 1, of it. The ratio is
+// checked every minute. It is disabled in read-only mode.
+func WithAutoCompact(threshold float64) Option {
+	return func(o *option) error {
+		if threshold <= 0 || threshold >= 1 {
+			return fmt.Errorf("auto compact threshold %v out of range (0, 1)", threshold)
+		}
+		o.autoCompact = threshold
+		return nil
+	}
+}
+
+// Compact rewrites the database file without its free pages, giving the
+// space back to the file system. Reads and writes wait until it is done.
+func (s *Store) Compact() (err error) {
+	if s.opt.Load().readOnly {
+		return bolt.ErrDatabaseReadOnly
+	}
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+	select {
+	case <-s.done:
+		return ErrClosed
+	default:
+	}
+	start, before := time.Now(), fileSize(s.path)
+	defer func() {
+		if err != nil {
+			s.logger().Error("gostore: compaction failed", "error", err)
+			return
+		}
+		s.logger().Info("gostore: compacted", "before", before, "after", fileSize(s.path), "duration", time.Since(start))
+	}()
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+	defer os.Remove(tmp)
+
+	dst, err := bolt.Open(tmp, _fileMode, &bolt.Options{NoSync: true})
+	if err != nil {
+		return err
+	}
+	err = bolt.Compact(dst, s.db, int64(s.maxTxSize()))
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compact: %w", err)
+	}
+
+	if err := s.db.Sync(); err != nil {
+		return err
+	}
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(tmp, s.path)
+	// reopen either file, the store is unusable without one
+	db, err := bolt.Open(s.path, _fileMode, &s.boltOpts)
+	if err != nil {
+		return fmt.Errorf("failed to reopen after compaction: %w", err)
+	}
+	s.db = db
+	return renameErr
+}
+
+// CompactOp is a ScheduledOp running Compact.
+func CompactOp(s *Store) error {
+	return s.Compact()
+}
+
+// fileSize returns the size of the file at path, zero if it cannot be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// freeRatio returns the fraction of the database file taken by free pages
+func (s *Store) freeRatio() (float64, error) {
+	var ratio float64
+	err := s.view(func(tx *bolt.Tx) error {
+		stats := s.db.Stats()
+		if size := tx.Size(); size > 0 {
+			free := int64(stats.FreePageN+stats.PendingPageN) * int64(s.db.Info().PageSize)
+			ratio = float64(free) / float64(size)
+		}
+		return nil
+	})
+	return ratio, err
+}
+
+// maybeCompact compacts the database file if it is past the threshold
+func (s *Store) maybeCompact() error {
+	threshold := s.opt.Load().autoCompact
+	if threshold <= 0 {
+		return nil
+	}
+	ratio, err := s.freeRatio()
+	if err != nil || ratio <= threshold {
+		return err
+	}
+	return s.Compact()
+}
+
+func (s *Store) runAutoCompact() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(_autoCompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			_ = s.maybeCompact()
+		}
+	}
+}
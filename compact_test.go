@@ -0,0 +1,68 @@
+package gostore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithAutoCompact(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var kvs []KV
+	for i := 0; i < 2000; i++ {
+		kvs = append(kvs, KV{Key: []byte(fmt.Sprintf("%04d", i)), Value: make([]byte, 1024)})
+	}
+	if _, err := s.PutBatch("ns", kvs); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Tx(func(tx *StoreTx) error {
+		for _, kv := range kvs[1:] {
+			if err := tx.Delete([]byte("ns"), kv.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ratio, err := s.freeRatio()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ratio <= 0.5 {
+		t.Fatalf("expected free ratio above 0.5, got %v", ratio)
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.maybeCompact(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() >= before.Size() {
+		t.Errorf("expected file to shrink from %d, got %d", before.Size(), after.Size())
+	}
+	if v, err := s.Get([]byte("ns"), kvs[0].Key); err != nil || len(v) != 1024 {
+		t.Errorf("expected 1024 bytes got %d, %v", len(v), err)
+	}
+	if err := s.Put("ns", []byte("after"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := WithAutoCompact(1)(&option{}); err == nil {
+		t.Error("expected error")
+	}
+}
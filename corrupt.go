@@ -0,0 +1,181 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CorruptNamespace holds the records quarantined by CorruptQuarantine, under
+// their namespace and key joined by a zero byte.
+const CorruptNamespace = "\x00corrupt"
+
+// CorruptPolicy decides what reads do with a record that cannot be decoded.
+type CorruptPolicy uint8
+
+const (
+	// CorruptFail returns ErrCorrupted and leaves the record alone, the
+	// default.
+	CorruptFail CorruptPolicy = iota
+	// CorruptDelete deletes the record.
+	CorruptDelete
+	// CorruptQuarantine moves the raw record to CorruptNamespace.
+	CorruptQuarantine
+	// CorruptRestore writes back the record from the newest backup holding
+	// a good copy of it, or quarantines it if none does.
+	CorruptRestore
+)
+
+// WithCorruptPolicy sets what Get, GetTTL and Load do with a record that
+// cannot be decoded. A deleted or quarantined record reads as
+// ErrKeyNotFound. backups is a filepath.Match pattern of the files written
+// by Backup or Snapshot, for CorruptRestore.
+//
+// Records carry no checksum: only a record whose length prefix does not
+// match its size is detected. A flipped bit inside a value, or in its
+// expiry, reads as good data and the policy does not apply to it.
+func WithCorruptPolicy(policy CorruptPolicy, backups string) Option {
+	return func(o *option) error {
+		if backups != "" {
+			if _, err := filepath.Match(backups, ""); err != nil {
+				return fmt.Errorf("bad backups pattern: %w", err)
+			}
+		}
+		o.corruptPolicy, o.corruptBackups = policy, backups
+		return nil
+	}
+}
+
+// repair applies the corrupt policy to a record found corrupted and returns
+// its restored value, or ErrKeyNotFound if it was removed
+func (s *Store) repair(namespace, key []byte) (*valueT, error) {
+	opt := s.opt.Load()
+	if opt.corruptPolicy == CorruptFail || isInternal(string(namespace)) {
+		return nil, ErrCorrupted
+	}
+	var restored *valueT
+	if opt.corruptPolicy == CorruptRestore {
+		restored = restoreRecord(opt.corruptBackups, namespace, key)
+	}
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return ErrKeyNotFound
+		}
+		raw := bucket.Get(key)
+		if raw == nil {
+			return ErrKeyNotFound
+		}
+		var value valueT
+		if value.UnmarshalBinary(raw) == nil {
+			// rewritten since it was read
+			restored = &value
+			return nil
+		}
+		if restored != nil {
+			buf, err := restored.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			return s.replaceCorrupt(tx, bucket, namespace, key, raw, buf)
+		}
+		if opt.corruptPolicy != CorruptDelete {
+			quarantine, err := tx.CreateBucketIfNotExists([]byte(CorruptNamespace))
+			if err != nil {
+				return err
+			}
+			qkey := append(append(append([]byte(nil), namespace...), 0), key...)
+			if err := quarantine.Put(qkey, append([]byte(nil), raw...)); err != nil {
+				return err
+			}
+		}
+		return s.replaceCorrupt(tx, bucket, namespace, key, raw, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: repair failed: %w", ErrCorrupted, err)
+	}
+	if restored == nil {
+		return nil, ErrKeyNotFound
+	}
+	return restored, nil
+}
+
+// replaceCorrupt writes buf over the corrupt record raw, or deletes it if
+// buf is nil. It goes straight to the bucket: the record is already there,
+// so no-overwrite, immutable and frozen namespaces must not refuse it.
+func (s *Store) replaceCorrupt(tx *bolt.Tx, bucket *bolt.Bucket, namespace, key, raw, buf []byte) error {
+	if q := s.quotas[string(namespace)]; q != nil {
+		q.current(tx, bucket)
+		if buf == nil {
+			q.add(tx, -1, -int64(len(key)+len(raw)))
+		} else {
+			q.add(tx, 0, int64(len(buf)-len(raw)))
+		}
+	}
+	op := OpPut
+	if buf == nil {
+		op = OpDelete
+		buf = append([]byte(nil), raw...)
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	} else if err := bucket.Put(key, buf); err != nil {
+		return err
+	}
+	s.invalidate(tx, namespace, key)
+	return s.changed(tx, op, namespace, key, buf)
+}
+
+// restoreRecord returns the record from the newest backup matching pattern
+// that has a good copy of it
+func restoreRecord(pattern string, namespace, key []byte) *valueT {
+	if pattern == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil
+	}
+	modTimes := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return modTimes[paths[i]].After(modTimes[paths[j]]) })
+	for _, path := range paths {
+		var value valueT
+		err := readBackup(path, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(namespace)
+			if bucket == nil {
+				return ErrKeyNotFound
+			}
+			raw := bucket.Get(key)
+			if raw == nil {
+				return ErrKeyNotFound
+			}
+			return value.UnmarshalBinary(raw)
+		})
+		if err == nil && !value.isExpired() {
+			return &value
+		}
+	}
+	return nil
+}
+
+// readBackup runs fn in a read transaction of the bolt file at path
+func readBackup(path string, fn func(tx *bolt.Tx) error) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := bolt.Open(path, _fileMode, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	return errors.Join(db.View(fn), db.Close())
+}
@@ -0,0 +1,106 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCorruptPolicy(t *testing.T) {
+	dir := t.TempDir()
+	ns, key := []byte("ns"), []byte("key")
+	for _, tc := range []struct {
+		policy      CorruptPolicy
+		value       string
+		err         error
+		quarantined bool
+	}{
+		{CorruptFail, "", ErrCorrupted, false},
+		{CorruptDelete, "", ErrKeyNotFound, false},
+		{CorruptQuarantine, "", ErrKeyNotFound, true},
+		{CorruptRestore, "good", nil, false},
+	} {
+		path := filepath.Join(dir, "store.db")
+		os.Remove(path)
+		backups := filepath.Join(dir, "backup-*.db")
+		s, err := Open(path, WithCorruptPolicy(tc.policy, backups))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Put(string(ns), key, []byte("good")); err != nil {
+			t.Error(err)
+		}
+		if err := s.Snapshot(filepath.Join(dir, "backup-1.db")); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(ns).Put(key, []byte("garbage"))
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := s.Get(ns, key)
+		if !errors.Is(err, tc.err) || string(v) != tc.value {
+			t.Errorf("policy %d: expected %q, %v got %q, %v", tc.policy, tc.value, tc.err, v, err)
+		}
+		// the second read sees the repaired record
+		if v, err = s.Get(ns, key); !errors.Is(err, tc.err) || string(v) != tc.value {
+			t.Errorf("policy %d: expected %q, %v again got %q, %v", tc.policy, tc.value, tc.err, v, err)
+		}
+		qkey := append(append(append([]byte(nil), ns...), 0), key...)
+		var raw []byte
+		if err := s.db.View(func(tx *bolt.Tx) error {
+			if b := tx.Bucket([]byte(CorruptNamespace)); b != nil {
+				raw = b.Get(qkey)
+			}
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+		if (raw != nil) != tc.quarantined || (raw != nil && string(raw) != "garbage") {
+			t.Errorf("policy %d: expected quarantined %v got %q", tc.policy, tc.quarantined, raw)
+		}
+		s.Close()
+	}
+}
+
+func TestCorruptPolicyProtectedNamespace(t *testing.T) {
+	dir := t.TempDir()
+	ns, key := []byte("ns"), []byte("key")
+	for i, protect := range []Option{WithNoOverwrite(string(ns)), WithImmutableNamespace(string(ns))} {
+		for _, tc := range []struct {
+			policy CorruptPolicy
+			value  string
+			err    error
+		}{
+			{CorruptRestore, "good", nil},
+			{CorruptQuarantine, "", ErrKeyNotFound},
+		} {
+			path := filepath.Join(dir, "store.db")
+			os.Remove(path)
+			backups := filepath.Join(dir, "backup-*.db")
+			s, err := Open(path, WithCorruptPolicy(tc.policy, backups), protect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put(string(ns), key, []byte("good")); err != nil {
+				t.Error(err)
+			}
+			if err := s.Snapshot(filepath.Join(dir, "backup-1.db")); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.db.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket(ns).Put(key, []byte("garbage"))
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := s.Get(ns, key); !errors.Is(err, tc.err) || string(v) != tc.value {
+				t.Errorf("option %d, policy %d: expected %q, %v got %q, %v", i, tc.policy, tc.value, tc.err, v, err)
+			}
+			s.Close()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package gostore
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Incr adds delta to the counter stored at key and returns the new value,
+// in a single transaction. A missing or expired key counts from zero. The
+// counter is an 8-byte little-endian value and keeps the expiry it has.
+func (s *Store) Incr(namespace, key []byte, delta int64) (n int64, err error) {
+	dk := s.diskKey(key)
+	err = s.update(func(tx *bolt.Tx) error {
+		value := valueT{}
+		if bucket := tx.Bucket(namespace); bucket != nil {
+			if v := bucket.Get(dk); v != nil {
+				if err := value.UnmarshalBinary(v); err != nil {
+					return permanentError{fmt.Errorf("%w: %w", ErrCorrupted, err)}
+				}
+				if value.isExpired() {
+					value = valueT{}
+				}
+			}
+		}
+		n = 0
+		if value.Value != nil {
+			if len(value.Value) != 8 {
+				return permanentError{fmt.Errorf("%w: not a counter", ErrBadValue)}
+			}
+			n = int64(binary.LittleEndian.Uint64(value.Value))
+		}
+		n += delta
+		value.Value = binary.LittleEndian.AppendUint64(nil, uint64(n))
+		buf, err := value.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := s.put(tx, namespace, dk, buf); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to increment key %s: %w", key, err)
+	}
+	return n, err
+}
@@ -0,0 +1,62 @@
+package gostore
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIncr(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	var out syncBuffer
+	s, err := Open(path, WithLogger(slog.New(slog.NewTextHandler(&out, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("ns"), []byte("counter")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Incr(ns, key, 2); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	n, err := s.Incr(ns, key, -5)
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 15 {
+		t.Errorf("expected 15 got %d", n)
+	}
+	v, err := s.Get(ns, key)
+	if err != nil {
+		t.Error(err)
+	}
+	if got := int64(binary.LittleEndian.Uint64(v)); got != 15 {
+		t.Errorf("expected 15 got %d", got)
+	}
+
+	if err := s.Put("ns", []byte("text"), []byte("abc")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Incr(ns, []byte("text"), 1); !errors.Is(err, ErrBadValue) {
+		t.Errorf("expected error %s, got %v", ErrBadValue, err)
+	}
+	if logged := out.String(); strings.Contains(logged, "retrying") {
+		t.Errorf("expected no retry of a bad value got:\n%s", logged)
+	}
+}
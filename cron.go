@@ -0,0 +1,207 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBadSchedule is returned by Schedule for a spec it cannot parse or
+// that never matches.
+var ErrBadSchedule = errors.New("bad schedule")
+
+// ScheduledOp is an operation run by Schedule. GCOp, CompactOp,
+// CleanTempOp and BackupOp are provided; other operations, or ones taking
+// arguments, are closures calling the store.
+type ScheduledOp func(s *Store) error
+
+// GCOp is a ScheduledOp running one garbage collection pass.
+func GCOp(s *Store) error {
+	_, err := s.GC()
+	return err
+}
+
+// Schedule runs op on a cron spec until the returned stop function is
+// called or the store is closed. The spec is either five fields (minute,
+// hour, day of month, month, day of week) supporting "*", lists, ranges and
+// steps, or one of "@every <duration>", "@hourly", "@daily", "@weekly",
+// "@monthly" and "@yearly". Times are local. Errors returned by op are
+// logged and the schedule carries on; runs never overlap.
+func (s *Store) Schedule(spec string, op ScheduledOp) (stop func(), err error) {
+	sched, err := parseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+	first, ok := sched.next(time.Now())
+	if !ok {
+		return nil, fmt.Errorf("%w: %q never matches", ErrBadSchedule, spec)
+	}
+	s.bgMu.Lock()
+	defer s.bgMu.Unlock()
+	select {
+	case <-s.done:
+		return nil, ErrClosed
+	default:
+	}
+	stopped := make(chan struct{})
+	var once sync.Once
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for next, ok := first, true; ok; next, ok = sched.next(time.Now()) {
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-s.done:
+				timer.Stop()
+				return
+			case <-stopped:
+				timer.Stop()
+				return
+			case <-timer.C:
+				if err := op(s); err != nil {
+					s.logger().Error("gostore: scheduled operation failed", "spec", spec, "error", err)
+				}
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(stopped) }) }, nil
+}
+
+type schedule interface {
+	// next returns the first run after t, false if there is none
+	next(t time.Time) (time.Time, bool)
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) next(t time.Time) (time.Time, bool) {
+	return t.Add(time.Duration(e)), true
+}
+
+// cronSchedule holds one bit per allowed value of each field.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record unrestricted day fields, see next
+	domStar, dowStar bool
+}
+
+var _cronDescriptors = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrBadSchedule, spec)
+		}
+		return everySchedule(every), nil
+	}
+	if expanded, ok := _cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q needs 5 fields", ErrBadSchedule, spec)
+	}
+	var (
+		c   cronSchedule
+		err error
+	)
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	for i, dst := range []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow} {
+		if *dst, err = parseCronField(fields[i], bounds[i][0], bounds[i][1]); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrBadSchedule, spec, err)
+		}
+	}
+	// 7 is Sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar, c.dowStar = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bitset uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q", part)
+			}
+		}
+		start, end := lo, hi
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("bad value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("bad value %q", part)
+				}
+			} else if hasStep {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bitset |= 1 << v
+		}
+	}
+	return bitset, nil
+}
+
+// next returns the first matching minute after t, false if none comes
+// within the search limit. As in cron, when both day fields are restricted
+// a day matches if either of them does.
+func (c *cronSchedule) next(t time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// February 29 comes back within 8 years, even across a century; a
+	// spec such as "0 0 31 2 *" never matches
+	limit := t.AddDate(9, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
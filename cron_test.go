@@ -0,0 +1,151 @@
+package gostore
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1-5", time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		// either day field may match when both are restricted
+		{"0 0 15 * 5", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"0,30 9-17/4 * * *", time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC)},
+		{"@every 90s", base.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		sched, err := parseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %s", tt.spec, err)
+			continue
+		}
+		if next, ok := sched.next(base); !ok || !next.Equal(tt.next) {
+			t.Errorf("%s: expected %s, got %s, %v", tt.spec, tt.next, next, ok)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "a * * * *", "@every -1s", "@often"} {
+		if _, err := parseSchedule(spec); !errors.Is(err, ErrBadSchedule) {
+			t.Errorf("%q: expected error %s, got %v", spec, ErrBadSchedule, err)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var runs atomic.Int32
+	stop, err := s.Schedule("@every 10ms", func(*Store) error {
+		runs.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return runs.Load() >= 2 })
+	stop()
+	n := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() > n+1 {
+		t.Errorf("expected no runs after stop, got %d", runs.Load()-n)
+	}
+
+	if _, err := s.Schedule("@every 1h", GCOp); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Schedule("0 0 31 2 *", GCOp); !errors.Is(err, ErrBadSchedule) {
+		t.Errorf("expected error %s, got %v", ErrBadSchedule, err)
+	}
+}
+
+func TestScheduleLogsErrors(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	var out syncBuffer
+	s, err := Open(path, WithLogger(slog.New(slog.NewTextHandler(&out, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	stop, err := s.Schedule("@every 10ms", func(*Store) error {
+		return errors.New("injected")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	waitFor(t, func() bool {
+		return strings.Contains(out.String(), "gostore: scheduled operation failed") &&
+			strings.Contains(out.String(), `spec="@every 10ms" error=injected`)
+	})
+}
+
+func TestScheduleOps(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Put("ns", []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+
+	dir := t.TempDir()
+	stopBackup, err := s.Schedule("@every 10ms", BackupOp(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopBackup()
+	stopCompact, err := s.Schedule("@every 10ms", CompactOp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopCompact()
+
+	var backups []string
+	waitFor(t, func() bool {
+		backups, _ = filepath.Glob(filepath.Join(dir, filepath.Base(path)+".*.bak"))
+		return len(backups) > 0
+	})
+	if err := checkFile(backups[0]); err != nil {
+		t.Errorf("expected a valid backup, got %v", err)
+	}
+	if v, err := s.Get([]byte("ns"), []byte("a")); err != nil || string(v) != "1" {
+		t.Errorf("expected 1 got %s, %v", v, err)
+	}
+}
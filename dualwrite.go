@@ -0,0 +1,261 @@
+package gostore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _dualWriteQueueSize = 1024
+
+// WithDualWrite mirrors every committed put and delete to target, in
+// commit order, while migrating to another backend. Expiry and namespace
+// deletions are not mirrored. Use DrainDualWrite and VerifyDualWrite to
+// confirm parity before switching. Close mirrors the changes still queued
+// before returning; changes committed after that count as failed. It is
+// disabled in read-only mode.
+func WithDualWrite(target KVStore) Option {
+	return func(o *option) error {
+		o.dualWrite = target
+		return nil
+	}
+}
+
+type dualWriter struct {
+	s      *Store
+	target KVStore
+	queue  chan Change
+	// mu guards closed, set once run has stopped taking changes
+	mu     sync.RWMutex
+	closed bool
+	// orderMu guards order and batches, see record
+	orderMu  sync.Mutex
+	order    []*dualBatch
+	batches  map[*bolt.Tx]*dualBatch
+	pending  atomic.Int64
+	mirrored atomic.Uint64
+	failed   atomic.Uint64
+}
+
+// dualBatch holds the changes of a write transaction until it is done
+type dualBatch struct {
+	changes         []Change
+	done, committed bool
+}
+
+func (s *Store) startDualWrite(target KVStore) {
+	d := &dualWriter{
+		s: s, target: target, queue: make(chan Change, _dualWriteQueueSize),
+		batches: make(map[*bolt.Tx]*dualBatch),
+	}
+	s.dual = d
+	s.wg.Add(1)
+	go d.run()
+}
+
+// record adds a change made by tx. tx holds the writer lock, so batches are
+// added in commit order; bolt runs commit handlers after releasing it, so
+// they are queued by finish in that order rather than as handlers run.
+func (d *dualWriter) record(tx *bolt.Tx, change Change) {
+	if change.Op == OpDeleteNamespace {
+		return
+	}
+	d.orderMu.Lock()
+	defer d.orderMu.Unlock()
+	b := d.batches[tx]
+	if b == nil {
+		b = &dualBatch{}
+		d.batches[tx] = b
+		d.order = append(d.order, b)
+		tx.OnCommit(func() { d.finish(tx, true) })
+	}
+	b.changes = append(b.changes, change)
+}
+
+// finish marks the batch of tx done and queues the changes of the done
+// batches at the head of the order, dropping those that did not commit
+func (d *dualWriter) finish(tx *bolt.Tx, committed bool) {
+	d.orderMu.Lock()
+	defer d.orderMu.Unlock()
+	b := d.batches[tx]
+	if b == nil {
+		return
+	}
+	delete(d.batches, tx)
+	b.done, b.committed = true, committed
+	for len(d.order) > 0 && d.order[0].done {
+		if d.order[0].committed {
+			for _, change := range d.order[0].changes {
+				d.enqueue(change)
+			}
+		}
+		d.order[0] = nil
+		d.order = d.order[1:]
+	}
+}
+
+func (d *dualWriter) enqueue(change Change) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.failed.Add(1)
+		return
+	}
+	d.pending.Add(1)
+	select {
+	case d.queue <- change:
+	case <-d.s.done:
+		d.pending.Add(-1)
+		d.failed.Add(1)
+	}
+}
+
+func (d *dualWriter) run() {
+	defer d.s.wg.Done()
+	for {
+		select {
+		case change := <-d.queue:
+			d.apply(change)
+		case <-d.s.done:
+			d.mu.Lock()
+			d.closed = true
+			d.mu.Unlock()
+			for {
+				select {
+				case change := <-d.queue:
+					d.apply(change)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *dualWriter) apply(change Change) {
+	defer d.pending.Add(-1)
+	var err error
+	if change.Op == OpPut {
+		err = d.target.Put(string(change.Namespace), change.Key, change.Value)
+	} else {
+		err = d.target.Delete(string(change.Namespace), change.Key)
+	}
+	if err != nil {
+		d.failed.Add(1)
+		return
+	}
+	d.mirrored.Add(1)
+}
+
+// DualWriteStats returns the number of changes mirrored to the dual-write
+// target and the number that failed.
+func (s *Store) DualWriteStats() (mirrored, failed uint64) {
+	if s.dual == nil {
+		return 0, 0
+	}
+	return s.dual.mirrored.Load(), s.dual.failed.Load()
+}
+
+// DrainDualWrite waits until all committed changes have been mirrored to
+// the dual-write target.
+func (s *Store) DrainDualWrite(ctx context.Context) error {
+	if s.dual == nil {
+		return nil
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.dual.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// VerifyDualWrite compares every record with the dual-write target and
+// returns the records that differ: live records the target misses or holds
+// another value for, and expired records it still holds. If the target is
+// a KeyLister, keys only present in the target are found too.
+func (s *Store) VerifyDualWrite() ([]ShadowMismatch, error) {
+	if s.dual == nil {
+		return nil, nil
+	}
+	var mismatches []ShadowMismatch
+	err := s.view(func(tx *bolt.Tx) error {
+		now := time.Now()
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if isInternal(string(name)) {
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				var valueErr error
+				value, ok := liveValue(v, now)
+				if !ok {
+					valueErr = ErrKeyExpired
+				}
+				got, gotErr := s.dual.target.Get(name, k)
+				if shadowMatch(value, valueErr, got, gotErr) {
+					return nil
+				}
+				mismatches = append(mismatches, ShadowMismatch{
+					Namespace: append([]byte(nil), name...), Key: append([]byte(nil), k...), Value: value, Err: valueErr,
+					ShadowValue: got, ShadowErr: gotErr,
+				})
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if lister, ok := s.dual.target.(KeyLister); ok {
+			extra, err := s.extraKeys(tx, lister)
+			if err != nil {
+				return err
+			}
+			mismatches = append(mismatches, extra...)
+		}
+		return nil
+	})
+	return mismatches, err
+}
+
+// extraKeys returns the keys of target that tx does not hold
+func (s *Store) extraKeys(tx *bolt.Tx, target KeyLister) ([]ShadowMismatch, error) {
+	names, err := target.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []ShadowMismatch
+	for _, name := range names {
+		if isInternal(name) {
+			continue
+		}
+		bucket := tx.Bucket([]byte(name))
+		for cursor := []byte(nil); ; {
+			keys, next, err := target.Keys([]byte(name), cursor, 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range keys {
+				if bucket != nil && bucket.Get(k) != nil {
+					continue
+				}
+				got, gotErr := s.dual.target.Get([]byte(name), k)
+				mismatches = append(mismatches, ShadowMismatch{
+					Namespace: []byte(name), Key: k, Err: ErrKeyNotFound,
+					ShadowValue: got, ShadowErr: gotErr,
+				})
+			}
+			if next == nil {
+				break
+			}
+			cursor = next
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,201 @@
+package gostore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDualWrite(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	target := newMapStore()
+	s, err := Open(path, WithDualWrite(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("ns", []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("ns", []byte("b"), []byte("2")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete("ns", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.DrainDualWrite(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mirrored, failed := s.DualWriteStats(); mirrored != 3 || failed != 0 {
+		t.Errorf("expected 3 mirrored and 0 failed, got %d and %d", mirrored, failed)
+	}
+	if _, err := target.Get([]byte("ns"), []byte("a")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+
+	mismatches, err := s.VerifyDualWrite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("expected no mismatches, got %+v", mismatches)
+	}
+	_ = target.Put("ns", []byte("b"), []byte("stale"))
+	if mismatches, _ := s.VerifyDualWrite(); len(mismatches) != 1 || string(mismatches[0].Key) != "b" {
+		t.Errorf("expected a mismatch for b, got %+v", mismatches)
+	}
+}
+
+// gatedStore is a mapStore whose writes wait for gate to be closed
+type gatedStore struct {
+	*mapStore
+	gate chan struct{}
+}
+
+func (g *gatedStore) Put(namespace string, key, value []byte) error {
+	<-g.gate
+	return g.mapStore.Put(namespace, key, value)
+}
+
+func TestDualWriteClose(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	target := &gatedStore{mapStore: newMapStore(), gate: make(chan struct{})}
+	s, err := Open(path, WithDualWrite(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 10
+	for i := 0; i < n; i++ {
+		if err := s.Put("ns", []byte(fmt.Sprint(i)), []byte("v")); err != nil {
+			t.Error(err)
+		}
+	}
+	closed := make(chan error)
+	go func() { closed <- s.Close() }()
+	time.Sleep(50 * time.Millisecond)
+	close(target.gate)
+	if err := <-closed; err != nil {
+		t.Error(err)
+	}
+	if pending := s.dual.pending.Load(); pending != 0 {
+		t.Errorf("expected no pending changes, got %d", pending)
+	}
+	if mirrored, failed := s.DualWriteStats(); mirrored != n || failed != 0 {
+		t.Errorf("expected %d mirrored and 0 failed, got %d and %d", n, mirrored, failed)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := target.Get([]byte("ns"), []byte(fmt.Sprint(i))); err != nil {
+			t.Errorf("expected key %d to be mirrored, got %v", i, err)
+		}
+	}
+}
+
+func TestDualWriteOrder(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	target := newMapStore()
+	s, err := Open(path, WithDualWrite(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := []byte(fmt.Sprint(i % 5))
+				if i%7 == w%7 {
+					_ = s.Delete("ns", key)
+				} else {
+					_ = s.Put("ns", key, []byte(fmt.Sprint(w, i)))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.DrainDualWrite(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		key := []byte(fmt.Sprint(i))
+		want, wantErr := s.Get([]byte("ns"), key)
+		got, gotErr := target.Get([]byte("ns"), key)
+		if !shadowMatch(want, wantErr, got, gotErr) {
+			t.Errorf("expected %s, %v for key %s, got %s, %v", want, wantErr, key, got, gotErr)
+		}
+	}
+}
+
+func TestVerifyDualWriteTargetOnly(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	targetPath, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(targetPath)
+	target, err := Open(targetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	s, err := Open(path, WithDualWrite(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.PutWithTTL([]byte("ns"), []byte("ttl"), []byte("v"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("ns", []byte("live"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.DrainDualWrite(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Put("ns", []byte("extra"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	mismatches, err := s.VerifyDualWrite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]error{}
+	for _, m := range mismatches {
+		found[string(m.Key)] = m.Err
+	}
+	if len(found) != 2 || found["ttl"] != ErrKeyExpired || found["extra"] != ErrKeyNotFound {
+		t.Errorf("expected mismatches for ttl and extra, got %+v", mismatches)
+	}
+}
@@ -0,0 +1,100 @@
+package gostore
+
+import (
+	"context"
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrorCode classifies the errors returned by the store, so callers can map
+// them without matching error strings.
+type ErrorCode uint8
+
+const (
+	// CodeOK means there was no error.
+	CodeOK ErrorCode = iota
+	// CodeUnknown is used for errors that fit no other code.
+	CodeUnknown
+	// CodeNotFound means the key or namespace does not exist.
+	CodeNotFound
+	// CodeExpired means the key exists but its TTL has passed.
+	CodeExpired
+	// CodeCorrupted means stored data could not be decoded.
+	CodeCorrupted
+	// CodeReadOnly means a write was attempted on a read-only store.
+	CodeReadOnly
+	// CodeQuota means a namespace quota, the memory budget or the
+	// transaction size limit was exceeded.
+	CodeQuota
+	// CodeTimeout means an operation gave up waiting.
+	CodeTimeout
+	// CodeConflict means the write lost against a concurrent change.
+	CodeConflict
+	// CodeClosed means the store was closed.
+	CodeClosed
+	// CodeInvalid means an argument or option was rejected, or the call
+	// needs a feature the store was opened without.
+	CodeInvalid
+	// CodeUnavailable means the operation was refused for now and may
+	// succeed later, such as a loader behind an open circuit breaker.
+	CodeUnavailable
+)
+
+var _codeNames = [...]string{
+	CodeOK:          "OK",
+	CodeUnknown:     "Unknown",
+	CodeNotFound:    "NotFound",
+	CodeExpired:     "Expired",
+	CodeCorrupted:   "Corrupted",
+	CodeReadOnly:    "ReadOnly",
+	CodeQuota:       "Quota",
+	CodeTimeout:     "Timeout",
+	CodeConflict:    "Conflict",
+	CodeClosed:      "Closed",
+	CodeInvalid:     "Invalid",
+	CodeUnavailable: "Unavailable",
+}
+
+// String returns the name of the code
+func (c ErrorCode) String() string {
+	if int(c) < len(_codeNames) {
+		return _codeNames[c]
+	}
+	return _codeNames[CodeUnknown]
+}
+
+// Code returns the ErrorCode of an error returned by the store
+func Code(err error) ErrorCode {
+	switch {
+	case err == nil:
+		return CodeOK
+	case errors.Is(err, ErrKeyNotFound), errors.Is(err, bolt.ErrBucketNotFound),
+		errors.Is(err, ErrNamespaceNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrKeyExpired):
+		return CodeExpired
+	case errors.Is(err, ErrCorrupted), errors.Is(err, bolt.ErrInvalid),
+		errors.Is(err, bolt.ErrChecksum), errors.Is(err, bolt.ErrVersionMismatch):
+		return CodeCorrupted
+	case errors.Is(err, bolt.ErrDatabaseReadOnly), errors.Is(err, bolt.ErrTxNotWritable),
+		errors.Is(err, ErrImmutable), errors.Is(err, ErrFrozen), errors.Is(err, ErrInternalNamespace),
+		errors.Is(err, ErrViewReadOnly):
+		return CodeReadOnly
+	case errors.Is(err, ErrQuotaExceeded), errors.Is(err, ErrMemoryBudget), errors.Is(err, ErrTxTooBig):
+		return CodeQuota
+	case errors.Is(err, bolt.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, ErrKeyExists):
+		return CodeConflict
+	case errors.Is(err, ErrClosed), errors.Is(err, bolt.ErrDatabaseNotOpen):
+		return CodeClosed
+	case errors.Is(err, ErrBadValue), errors.Is(err, ErrHashedKeys), errors.Is(err, ErrBadView),
+		errors.Is(err, ErrBadPartitions), errors.Is(err, ErrBadSchedule), errors.Is(err, ErrNoChangeLog),
+		errors.Is(err, ErrNotReconfigurable):
+		return CodeInvalid
+	case errors.Is(err, ErrCircuitOpen):
+		return CodeUnavailable
+	}
+	return CodeUnknown
+}
@@ -0,0 +1,73 @@
+package gostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code ErrorCode
+	}{
+		{nil, CodeOK},
+		{errors.New("other"), CodeUnknown},
+		{ErrKeyNotFound, CodeNotFound},
+		{fmt.Errorf("wrapped: %w", ErrKeyExpired), CodeExpired},
+		{ErrCorrupted, CodeCorrupted},
+		{bolt.ErrDatabaseReadOnly, CodeReadOnly},
+		{ErrViewReadOnly, CodeReadOnly},
+		{fmt.Errorf("failed to put key a: %w", ErrQuotaExceeded), CodeQuota},
+		{context.DeadlineExceeded, CodeTimeout},
+		{fmt.Errorf("failed to export a: %w", ErrNamespaceNotFound), CodeNotFound},
+		{ErrMemoryBudget, CodeQuota},
+		{ErrTxTooBig, CodeQuota},
+		{ErrKeyExists, CodeConflict},
+		{ErrClosed, CodeClosed},
+		{bolt.ErrDatabaseNotOpen, CodeClosed},
+		{fmt.Errorf("%w: not a counter", ErrBadValue), CodeInvalid},
+		{ErrHashedKeys, CodeInvalid},
+		{ErrBadView, CodeInvalid},
+		{ErrBadPartitions, CodeInvalid},
+		{ErrBadSchedule, CodeInvalid},
+		{ErrNoChangeLog, CodeInvalid},
+		{ErrNotReconfigurable, CodeInvalid},
+		{ErrCircuitOpen, CodeUnavailable},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.code {
+			t.Errorf("expected code %s for %v, got %s", tt.code, tt.err, got)
+		}
+	}
+}
+
+func TestCodeCorrupted(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("test"))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("key"), []byte{0xff, 0xff, 0xff, 0x7f})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get([]byte("test"), []byte("key")); Code(err) != CodeCorrupted {
+		t.Errorf("expected code %s, got %s", CodeCorrupted, Code(err))
+	}
+}
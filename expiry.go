@@ -0,0 +1,60 @@
+package gostore
+
+import (
+	"bytes"
+	"time"
+)
+
+const _expiredQueueSize = 1024
+
+// KeyEvent reports a key found expired.
+type KeyEvent struct {
+	Namespace []byte
+	Key       []byte
+	Expire    time.Time
+}
+
+// ExpiredKeys returns a channel receiving the keys deleted by the garbage
+// collector and the keys reads found expired. Delivery is best-effort:
+// events are dropped while the channel is full, and a key read after it
+// expired may be reported more than once. The channel is closed by Close.
+func (s *Store) ExpiredKeys() <-chan KeyEvent {
+	s.expiredMu.Lock()
+	defer s.expiredMu.Unlock()
+	if s.expired == nil {
+		s.expired = make(chan KeyEvent, _expiredQueueSize)
+		select {
+		case <-s.done:
+			close(s.expired)
+			s.expiredClosed = true
+		default:
+		}
+	}
+	return s.expired
+}
+
+// notifyExpired runs the OnExpire hook and sends a KeyEvent if anyone
+// called ExpiredKeys
+func (s *Store) notifyExpired(namespace, key []byte, expire time.Time) {
+	if h := s.opt.Load().hooks.OnExpire; h != nil && !isInternal(string(namespace)) {
+		h(namespace, key, expire)
+	}
+	s.expiredMu.Lock()
+	defer s.expiredMu.Unlock()
+	if s.expired == nil || s.expiredClosed || isInternal(string(namespace)) {
+		return
+	}
+	select {
+	case s.expired <- KeyEvent{Namespace: bytes.Clone(namespace), Key: bytes.Clone(key), Expire: expire}:
+	default:
+	}
+}
+
+func (s *Store) closeExpired() {
+	s.expiredMu.Lock()
+	defer s.expiredMu.Unlock()
+	if s.expired != nil && !s.expiredClosed {
+		close(s.expired)
+		s.expiredClosed = true
+	}
+}
@@ -0,0 +1,53 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExpiredKeys(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := s.ExpiredKeys()
+
+	ns := []byte("ns")
+	if err := s.PutWithTTL(ns, []byte("a"), []byte("1"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL(ns, []byte("b"), []byte("2"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+
+	if _, err := s.Get(ns, []byte("a")); err != ErrKeyExpired {
+		t.Errorf("expected error %s, got %v", ErrKeyExpired, err)
+	}
+	if e := <-events; string(e.Namespace) != "ns" || string(e.Key) != "a" {
+		t.Errorf("unexpected event %s/%s", e.Namespace, e.Key)
+	}
+	if _, err := s.GC(); err != nil {
+		t.Error(err)
+	}
+	got := map[string]bool{}
+	for len(events) > 0 {
+		got[string((<-events).Key)] = true
+	}
+	if !got["a"] || !got["b"] {
+		t.Errorf("expected events for a and b, got %v", got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed")
+	}
+}
@@ -0,0 +1,117 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNamespaceNotFound is returned when a namespace does not exist.
+var ErrNamespaceNotFound = errors.New("namespace not found")
+
+// ExportNamespace writes a standalone bolt file holding only namespace to w.
+// The file can be opened as a store or loaded into another one with
+// ImportNamespaceFile.
+func (s *Store) ExportNamespace(namespace string, w io.Writer) error {
+	f, err := os.CreateTemp("", "gostore-export-*")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	dst, err := bolt.Open(path, _fileMode, &bolt.Options{NoSync: true})
+	if err != nil {
+		return err
+	}
+	err = s.view(func(tx *bolt.Tx) error {
+		src := tx.Bucket([]byte(namespace))
+		if src == nil || isInternal(namespace) {
+			return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+		return dst.Update(func(dtx *bolt.Tx) error {
+			bucket, err := dtx.CreateBucket([]byte(namespace))
+			if err != nil {
+				return err
+			}
+			// keys arrive in order, pack the pages
+			bucket.FillPercent = 1
+			if err := bucket.SetSequence(src.Sequence()); err != nil {
+				return err
+			}
+			return src.ForEach(bucket.Put)
+		})
+	})
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// ImportNamespaceFile loads the namespaces of a bolt file written by
+// ExportNamespace, overwriting existing keys. It returns the number of
+// records imported. With WithHashedKeys the keys are hashed as they are
+// imported, so the file must come from a store without it.
+func (s *Store) ImportNamespaceFile(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	src, err := bolt.Open(path, _fileMode, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	total := 0
+	limit := s.maxTxSize()
+	err = src.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if isInternal(string(name)) {
+				return nil
+			}
+			var (
+				batch []KV
+				size  int
+			)
+			flush := func() error {
+				n, err := s.putSplit(name, batch)
+				total += n
+				batch, size = batch[:0], 0
+				return err
+			}
+			if err := b.ForEach(func(k, v []byte) error {
+				if v == nil {
+					return nil
+				}
+				if _, ok := expireOf(v); !ok {
+					return fmt.Errorf("%w: %s/%s", ErrCorrupted, name, k)
+				}
+				batch = append(batch, KV{Key: bytes.Clone(s.diskKey(k)), Value: bytes.Clone(v)})
+				if size += len(k) + len(v); size >= limit {
+					return flush()
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			return flush()
+		})
+	})
+	return total, err
+}
@@ -0,0 +1,72 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestExportNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("tenant", []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL([]byte("tenant"), []byte("b"), []byte("2"), 3600); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("other", []byte("c"), []byte("3")); err != nil {
+		t.Error(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.ExportNamespace("tenant", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ExportNamespace("missing", &buf); !errors.Is(err, ErrNamespaceNotFound) {
+		t.Errorf("expected error %s, got %v", ErrNamespaceNotFound, err)
+	}
+
+	exported, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(exported)
+	if err := os.WriteFile(exported, buf.Bytes(), _fileMode); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(target)
+	s2, err := Open(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	n, err := s2.ImportNamespaceFile(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 got %d", n)
+	}
+	if v, err := s2.Get([]byte("tenant"), []byte("b")); err != nil || string(v) != "2" {
+		t.Errorf("expected 2 got %s, %v", v, err)
+	}
+	if _, err := s2.Get([]byte("other"), []byte("c")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
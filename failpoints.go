@@ -0,0 +1,37 @@
+package gostore
+
+// Failpoint is called at a fixed point of an operation. It can sleep to
+// inject latency or return an error to inject a failure.
+type Failpoint func() error
+
+// Failpoints holds the failpoints used for chaos testing. Nil failpoints
+// are skipped.
+type Failpoints struct {
+	// BeforeCommit runs at the end of every write transaction. An error
+	// rolls the transaction back.
+	BeforeCommit Failpoint
+	// AfterMarshal runs after a record has been encoded and before it is
+	// written. An error fails the write.
+	AfterMarshal Failpoint
+	// OnCacheAdd runs before a value is added to the LRU cache. An error
+	// skips the add, as if the entry had been evicted right away.
+	OnCacheAdd Failpoint
+	// BeforeRead runs before every attempt to begin a read transaction. An
+	// error fails the attempt, as a failure to begin it would.
+	BeforeRead Failpoint
+}
+
+// WithFailpoints enables injected errors and latency at the given points
+func WithFailpoints(fp Failpoints) Option {
+	return func(o *option) error {
+		o.failpoints = fp
+		return nil
+	}
+}
+
+func (fp Failpoint) eval() error {
+	if fp == nil {
+		return nil
+	}
+	return fp()
+}
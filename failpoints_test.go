@@ -0,0 +1,60 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFailpoints(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	errInjected := errors.New("injected")
+	var beforeCommit, afterMarshal, onCacheAdd error
+	calls := 0
+	s, err := Open(path, WithMaxCacheSize(10), WithFailpoints(Failpoints{
+		BeforeCommit: func() error { calls++; return beforeCommit },
+		AfterMarshal: func() error { return afterMarshal },
+		OnCacheAdd:   func() error { return onCacheAdd },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	beforeCommit = errInjected
+	if err := s.Put("test", []byte("key"), []byte("value")); !errors.Is(err, errInjected) {
+		t.Errorf("expected error %s, got %s", errInjected, err)
+	}
+	if calls != _defaultNumRetries {
+		t.Errorf("expected %d attempts, got %d", _defaultNumRetries, calls)
+	}
+	if _, err := s.Get([]byte("test"), []byte("key")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %s", ErrKeyNotFound, err)
+	}
+	beforeCommit = nil
+
+	afterMarshal = errInjected
+	if err := s.Put("test", []byte("key"), []byte("value")); !errors.Is(err, errInjected) {
+		t.Errorf("expected error %s, got %s", errInjected, err)
+	}
+	afterMarshal = nil
+
+	onCacheAdd = errInjected
+	if err := s.Update("test", &T1{Name: "value"}); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.lru.Get("test"); ok {
+		t.Error("expected test not to be cached")
+	}
+	var v T1
+	if err := s.Load("test", &v); err != nil {
+		t.Error(err)
+	}
+	if v.Name != "value" {
+		t.Errorf("expected value %s, got %s", "value", v.Name)
+	}
+}
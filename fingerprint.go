@@ -0,0 +1,88 @@
+package gostore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Fingerprint returns a hash of the live records of every namespace, with
+// their expiry. Stores holding the same records have the same fingerprint,
+// whatever their write history or file layout. Records expire, so
+// fingerprints compared across stores should be taken at about the same
+// time. It is the hash of the namespace fingerprints, see
+// NamespaceFingerprints.
+func (s *Store) Fingerprint() (string, error) {
+	sums, names, err := s.fingerprints()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, name := range names {
+		writeField(h, []byte(name))
+		h.Write(sums[name])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NamespaceFingerprints returns the fingerprint of the live records of each
+// namespace, to find which ones differ when Fingerprint does.
+func (s *Store) NamespaceFingerprints() (map[string]string, error) {
+	sums, _, err := s.fingerprints()
+	if err != nil {
+		return nil, err
+	}
+	fps := make(map[string]string, len(sums))
+	for name, sum := range sums {
+		fps[name] = hex.EncodeToString(sum)
+	}
+	return fps, nil
+}
+
+// fingerprints hashes every namespace in one read transaction and returns
+// the sums with the names in order
+func (s *Store) fingerprints() (map[string][]byte, []string, error) {
+	sums := make(map[string][]byte)
+	var names []string
+	err := s.view(func(tx *bolt.Tx) error {
+		now := time.Now()
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if isInternal(string(name)) {
+				return nil
+			}
+			h := sha256.New()
+			var expire [8]byte
+			c := bucket.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				var value valueT
+				if v == nil || value.UnmarshalBinary(v) != nil {
+					continue
+				}
+				if !value.Expire.IsZero() && now.After(value.Expire) {
+					continue
+				}
+				writeField(h, k)
+				writeField(h, value.Value)
+				binary.LittleEndian.PutUint64(expire[:], uint64(value.Expire.Unix()))
+				h.Write(expire[:])
+			}
+			names = append(names, string(name))
+			sums[string(name)] = h.Sum(nil)
+			return nil
+		})
+	})
+	return sums, names, err
+}
+
+// writeField writes b to h prefixed by its length, so field boundaries
+// can't shift between records
+func writeField(h hash.Hash, b []byte) {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(b)))
+	h.Write(n[:])
+	h.Write(b)
+}
@@ -0,0 +1,68 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	var stores []*Store
+	for i := 0; i < 2; i++ {
+		path, err := tempfile()
+		if err != nil {
+			t.Error(err)
+		}
+		defer os.RemoveAll(path)
+		s, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		stores = append(stores, s)
+	}
+	a, b := stores[0], stores[1]
+
+	// the same records written in another order
+	for _, kv := range [][2]string{{"x", "1"}, {"y", "2"}, {"z", "3"}} {
+		if err := a.Put("ns", []byte(kv[0]), []byte(kv[1])); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, kv := range [][2]string{{"z", "3"}, {"w", "0"}, {"x", "1"}, {"y", "2"}} {
+		if err := b.Put("ns", []byte(kv[0]), []byte(kv[1])); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := b.Delete("ns", []byte("w")); err != nil {
+		t.Error(err)
+	}
+	fa, err := a.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb, err := b.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fa != fb {
+		t.Errorf("expected equal fingerprints got %s and %s", fa, fb)
+	}
+
+	if err := b.Put("other", []byte("x"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if fb, _ = b.Fingerprint(); fa == fb {
+		t.Error("expected fingerprints to differ")
+	}
+	na, err := a.NamespaceFingerprints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, err := b.NamespaceFingerprints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if na["ns"] != nb["ns"] || len(na) != 1 || len(nb) != 2 {
+		t.Errorf("expected only other to differ got %v and %v", na, nb)
+	}
+}
@@ -0,0 +1,48 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFreezeNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("users", []byte("ann"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	s.FreezeNamespace("users")
+	if err := s.Put("users", []byte("bob"), []byte("2")); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected error %s, got %v", ErrFrozen, err)
+	}
+	if err := s.Delete("users", []byte("ann")); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected error %s, got %v", ErrFrozen, err)
+	}
+	if err := s.DeleteNamespace("users"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected error %s, got %v", ErrFrozen, err)
+	}
+	if Code(ErrFrozen) != CodeReadOnly {
+		t.Errorf("expected code %s got %s", CodeReadOnly, Code(ErrFrozen))
+	}
+	if v, err := s.Get([]byte("users"), []byte("ann")); err != nil || string(v) != "1" {
+		t.Errorf("expected 1 got %s, %v", v, err)
+	}
+	if err := s.Put("other", []byte("bob"), []byte("2")); err != nil {
+		t.Error(err)
+	}
+
+	s.ThawNamespace("users")
+	if err := s.Put("users", []byte("bob"), []byte("2")); err != nil {
+		t.Error(err)
+	}
+}
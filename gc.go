@@ -0,0 +1,217 @@
+package gostore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_defaultGCBatchSize = 1000
+	_defaultGCPause     = time.Millisecond
+)
+
+// GCPacing limits how much work the TTL garbage collector does, so it never
+// holds the bolt write lock for long.
+type GCPacing struct {
+	// MaxKeys is the maximum number of expired keys deleted per pass. The
+	// next pass resumes where this one stopped. Zero means unlimited.
+	MaxKeys int
+	// MaxDuration is the maximum duration of a pass. Zero means unlimited.
+	MaxDuration time.Duration
+	// BatchSize is the number of keys examined per write transaction.
+	BatchSize int
+	// Pause is how long to wait between transactions, giving foreground
+	// writes a chance to take the write lock.
+	Pause time.Duration
+}
+
+// WithGCInterval runs the TTL garbage collector every interval, deleting
+// expired records. It is disabled in read-only mode.
+func WithGCInterval(interval time.Duration) Option {
+	return func(o *option) error {
+		o.gcInterval = interval
+		return nil
+	}
+}
+
+// WithGCPacing sets how the TTL garbage collector spreads its work
+func WithGCPacing(p GCPacing) Option {
+	return func(o *option) error {
+		o.gcPacing = p
+		return nil
+	}
+}
+
+// gcCursor is the position where the next garbage collection batch starts.
+type gcCursor struct {
+	namespace []byte
+	key       []byte
+}
+
+// GC runs one garbage collection pass and returns the number of expired
+// records deleted.
+func (s *Store) GC() (total int, err error) {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			s.logger().Error("gostore: gc failed", "deleted", total, "error", err)
+		} else if total > 0 {
+			s.logger().Info("gostore: gc pass", "deleted", total, "duration", time.Since(start))
+		} else {
+			s.logger().Debug("gostore: gc pass", "deleted", total, "duration", time.Since(start))
+		}
+	}()
+
+	p := s.opt.Load().gcPacing
+	if p.BatchSize <= 0 {
+		p.BatchSize = _defaultGCBatchSize
+	}
+	if p.Pause == 0 {
+		p.Pause = _defaultGCPause
+	}
+	for {
+		limit := p.BatchSize
+		if p.MaxKeys > 0 && p.MaxKeys-total < limit {
+			limit = p.MaxKeys - total
+		}
+		next, deleted, done, err := s.gcBatch(s.gcNext, limit)
+		if err != nil {
+			return total, err
+		}
+		s.gcNext = next
+		total += deleted
+		if done {
+			s.gcNext = gcCursor{}
+			return total, nil
+		}
+		if p.MaxKeys > 0 && total >= p.MaxKeys {
+			return total, nil
+		}
+		if p.MaxDuration > 0 && time.Since(start) >= p.MaxDuration {
+			return total, nil
+		}
+		time.Sleep(p.Pause)
+	}
+}
+
+// gcBatch examines up to limit records starting at from and deletes the
+// expired ones. done is true once the last namespace has been scanned.
+func (s *Store) gcBatch(from gcCursor, limit int) (next gcCursor, deleted int, done bool, err error) {
+	err = s.update(func(tx *bolt.Tx) error {
+		next, deleted, done = from, 0, false
+		var names [][]byte
+		if err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if bytes.Compare(name, from.namespace) >= 0 && gcCollectable(name) && !s.isImmutable(name) && !s.isFrozen(name) &&
+				!s.isView(string(name)) {
+				// views lose their records with the source keys
+				names = append(names, append([]byte(nil), name...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		examined := 0
+		for _, name := range names {
+			var seek []byte
+			if bytes.Equal(name, from.namespace) {
+				seek = from.key
+			}
+			var (
+				expired [][]byte
+				expires []time.Time
+			)
+			c := tx.Bucket(name).Cursor()
+			k, v := c.Seek(seek)
+			for ; k != nil && examined < limit; k, v = c.Next() {
+				examined++
+				if expire, ok := expireOf(v); ok && !expire.IsZero() && now.After(expire) {
+					expired = append(expired, append([]byte(nil), k...))
+					expires = append(expires, expire)
+				}
+			}
+			var resume []byte
+			if k != nil {
+				resume = append([]byte(nil), k...)
+			}
+			for _, key := range expired {
+				var err error
+				if isInternal(string(name)) {
+					// the store's own records have no views, quotas or watchers
+					err = tx.Bucket(name).Delete(key)
+				} else {
+					err = s.delete(tx, name, key)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			deleted += len(expired)
+			if len(expired) > 0 {
+				tx.OnCommit(func() {
+					for i, key := range expired {
+						s.notifyExpired(name, key, expires[i])
+					}
+				})
+			}
+			if resume != nil {
+				next = gcCursor{namespace: name, key: resume}
+				return nil
+			}
+		}
+		done = true
+		return nil
+	})
+	return next, deleted, done, err
+}
+
+// gcCollectable reports whether a bucket holds records with a TTL
+func gcCollectable(name []byte) bool {
+	return !isInternal(string(name)) || string(name) == _idempotencyBucket
+}
+
+// expireOf decodes only the expiry of an encoded valueT.
+func expireOf(buf []byte) (time.Time, bool) {
+	if len(buf) < 4 {
+		return time.Time{}, false
+	}
+	n := int(int32(binary.LittleEndian.Uint32(buf)))
+	if n < 0 || len(buf) != 4+n+8 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.LittleEndian.Uint64(buf[4+n:])), 0), true
+}
+
+func (s *Store) runGC() {
+	defer s.wg.Done()
+	for {
+		var (
+			timer *time.Timer
+			tick  <-chan time.Time
+		)
+		if interval := s.opt.Load().gcInterval; interval > 0 {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+		select {
+		case <-s.done:
+		case <-s.gcReset:
+		case <-tick:
+			_, _ = s.GC()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+	}
+}
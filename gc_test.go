@@ -0,0 +1,72 @@
+package gostore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGC(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithGCPacing(GCPacing{MaxKeys: 2, BatchSize: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		ns := []byte(fmt.Sprintf("ns%d", i%2))
+		if err := s.PutWithTTL(ns, []byte(fmt.Sprintf("expired%d", i)), []byte("value"), 1); err != nil {
+			t.Error(err)
+		}
+		if err := s.PutWithTTL(ns, []byte(fmt.Sprintf("live%d", i)), []byte("value"), 0); err != nil {
+			t.Error(err)
+		}
+	}
+	time.Sleep(2 * time.Second)
+
+	for _, want := range []int{2, 2, 1, 0} {
+		n, err := s.GC()
+		if err != nil {
+			t.Error(err)
+		}
+		if n != want {
+			t.Errorf("expected %d keys collected, got %d", want, n)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		ns := []byte(fmt.Sprintf("ns%d", i%2))
+		if _, err := s.get(ns, []byte(fmt.Sprintf("expired%d", i))); err != ErrKeyNotFound {
+			t.Errorf("expected error %s, got %s", ErrKeyNotFound, err)
+		}
+		if _, err := s.Get(ns, []byte(fmt.Sprintf("live%d", i))); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestGCInterval(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithGCInterval(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.PutWithTTL([]byte("test"), []byte("key"), []byte("value"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2200 * time.Millisecond)
+	if _, err := s.get([]byte("test"), []byte("key")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %s", ErrKeyNotFound, err)
+	}
+}
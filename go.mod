@@ -6,6 +6,8 @@ toolchain go1.23.0
 
 require (
 	go.etcd.io/bbolt v1.3.11
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
 	golang.org/x/sync v0.5.0
 )
 
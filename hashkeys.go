@@ -0,0 +1,107 @@
+package gostore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_metaBucket    = _internalPrefix + "meta"
+	_metaHashedKey = "hashed_keys"
+)
+
+// ErrHashedKeys is returned for an operation on key prefixes of a store
+// opened with WithHashedKeys, and by Open for a file written with the
+// other key mode.
+var ErrHashedKeys = errors.New("keys are hashed")
+
+// WithHashedKeys stores the SHA-256 of every key instead of the key, so
+// identifiers such as emails or tokens never reach the file in readable
+// form. Every method taking keys hashes them transparently, from Get and
+// Put to CAS, Incr, the batch methods, Tx and the imports. Methods
+// returning stored keys, such as Scan, Keys, Watch and the change log, see
+// the hashes. Key prefixes and bounds cannot match hashes, so Scan, Range,
+// StreamKeys and DeletePrefix fail with ErrHashedKeys for a non-empty one,
+// and so do the files of FS. The mode is recorded in the file when it is
+// first opened writable, and opening it in the other mode fails with
+// ErrHashedKeys.
+func WithHashedKeys() Option {
+	return func(o *option) error {
+		o.hashedKeys = true
+		return nil
+	}
+}
+
+// diskKey returns the stored form of key
+func (s *Store) diskKey(key []byte) []byte {
+	if !s.opt.Load().hashedKeys {
+		return key
+	}
+	sum := sha256.Sum256(key)
+	return sum[:]
+}
+
+// diskKeys returns the stored form of keys
+func (s *Store) diskKeys(keys [][]byte) [][]byte {
+	if !s.opt.Load().hashedKeys {
+		return keys
+	}
+	hashed := make([][]byte, len(keys))
+	for i, k := range keys {
+		hashed[i] = s.diskKey(k)
+	}
+	return hashed
+}
+
+// checkKeyMode records whether the keys of db are hashed, or compares the
+// recorded mode with the one of opt. Files written before the mode was
+// recorded hold plain keys if they have any namespace.
+func checkKeyMode(db *bolt.DB, opt *option) error {
+	want := byte(0)
+	if opt.hashedKeys {
+		want = 1
+	}
+	var (
+		got      []byte
+		recorded bool
+	)
+	if err := db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket([]byte(_metaBucket)); meta != nil {
+			got = bytes.Clone(meta.Get([]byte(_metaHashedKey)))
+		}
+		if recorded = got != nil; recorded {
+			return nil
+		}
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if !isInternal(string(name)) {
+				got = []byte{0}
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	if got == nil {
+		got = []byte{want}
+	}
+	if len(got) != 1 || got[0] != want {
+		if opt.hashedKeys {
+			return fmt.Errorf("%w: file has plain keys", ErrHashedKeys)
+		}
+		return fmt.Errorf("%w: file was written WithHashedKeys", ErrHashedKeys)
+	}
+	if recorded || opt.readOnly {
+		return nil
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists([]byte(_metaBucket))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(_metaHashedKey), got)
+	})
+}
@@ -0,0 +1,238 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestHashedKeys(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithHashedKeys(), WithMaxCacheSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	key := []byte("ann@example.com")
+	if err := s.Put("users", key, []byte("ann")); err != nil {
+		t.Error(err)
+	}
+	if v, err := s.Get([]byte("users"), key); err != nil || string(v) != "ann" {
+		t.Errorf("expected ann got %s, %v", v, err)
+	}
+	keys, _, err := s.Keys([]byte("users"), nil, 0)
+	sum := sha256.Sum256(key)
+	if err != nil || len(keys) != 1 || !bytes.Equal(keys[0], sum[:]) {
+		t.Errorf("expected the hash of the key got %x, %v", keys, err)
+	}
+
+	if err := s.Update("session", &T1{Name: "token"}); err != nil {
+		t.Error(err)
+	}
+	var item T1
+	if err := s.Load("session", &item); err != nil || item.Name != "token" {
+		t.Errorf("expected token got %+v, %v", item, err)
+	}
+	if err := s.Remove("session"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Load("session", &item); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+
+	if err := s.Delete("users", key); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get([]byte("users"), key); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestHashedKeysEveryMethod(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithHashedKeys(), WithChangeLog(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := []byte("users")
+	get := func(key string, want string) {
+		t.Helper()
+		if v, err := s.Get(ns, []byte(key)); err != nil || string(v) != want {
+			t.Errorf("%s: expected %s got %s, %v", key, want, v, err)
+		}
+	}
+	if _, err := s.PutNX(ns, []byte("nx@example.com"), []byte("1"), 0); err != nil {
+		t.Error(err)
+	}
+	get("nx@example.com", "1")
+	if ok, err := s.CAS(ns, []byte("nx@example.com"), []byte("1"), []byte("2")); !ok || err != nil {
+		t.Errorf("expected a swap got %v, %v", ok, err)
+	}
+	get("nx@example.com", "2")
+	if v, loaded, err := s.GetOrSet(ns, []byte("nx@example.com"), []byte("3"), 0); !loaded || string(v) != "2" || err != nil {
+		t.Errorf("expected 2 got %s, %v, %v", v, loaded, err)
+	}
+	if _, err := s.Incr(ns, []byte("bob@example.com"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.Touch(ns, []byte("bob@example.com"), 60); err != nil {
+		t.Error(err)
+	}
+	if _, remaining, err := s.GetTTL(ns, []byte("bob@example.com")); err != nil || remaining <= 0 {
+		t.Errorf("expected a ttl got %s, %v", remaining, err)
+	}
+	if ttls, err := s.TTLMulti(ns, []byte("bob@example.com"), []byte("nx@example.com")); err != nil || ttls[0] <= 0 || ttls[1] != 0 {
+		t.Errorf("expected ttls got %v, %v", ttls, err)
+	}
+	if _, err := s.PutBatch("users", []KV{{Key: []byte("batch@example.com"), Value: []byte("b")}}); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutBatchAtomic("users", []KV{{Key: []byte("atomic@example.com"), Value: []byte("a")}}); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutMulti([]Entry{{Namespace: ns, Key: []byte("multi@example.com"), Value: []byte("m")}}); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.PutSeq("users", []byte("seq@example.com"), []byte("s")); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutIdempotent("users", []byte("idem@example.com"), []byte("i"), "token"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Tx(func(tx *StoreTx) error {
+		if err := tx.Put(ns, []byte("tx@example.com"), []byte("t")); err != nil {
+			return err
+		}
+		_, err := tx.Get(ns, []byte("tx@example.com"))
+		return err
+	}); err != nil {
+		t.Error(err)
+	}
+	get("batch@example.com", "b")
+	get("atomic@example.com", "a")
+	get("multi@example.com", "m")
+	get("seq@example.com", "s")
+	get("idem@example.com", "i")
+	get("tx@example.com", "t")
+	values, err := s.MGet(ns, []byte("batch@example.com"), []byte("multi@example.com"))
+	if err != nil || string(values[0]) != "b" || string(values[1]) != "m" {
+		t.Errorf("expected b and m got %q, %v", values, err)
+	}
+	if err := s.Update("load@example.com", &T1{Name: "l"}); err != nil {
+		t.Error(err)
+	}
+	var item T1
+	if errs, err := s.LoadMany(context.Background(), map[string]encoding.BinaryUnmarshaler{"load@example.com": &item}); err != nil || len(errs) != 0 || item.Name != "l" {
+		t.Errorf("expected l got %+v, %v, %v", item, errs, err)
+	}
+	if _, err := s.DeletePrefix(ns, []byte("bob")); err != ErrHashedKeys {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	if _, err := s.Scan(ns, []byte("bob"), 0); err != ErrHashedKeys {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	if err := s.Range(ns, []byte("a"), nil, func(k, v []byte) error { return nil }); err != ErrHashedKeys {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	_, errc := s.StreamKeys(context.Background(), ns, []byte("bob"))
+	if err := <-errc; err != ErrHashedKeys {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	if kvs, err := s.Scan(ns, nil, 0); err != nil || len(kvs) == 0 {
+		t.Errorf("expected records got %d, %v", len(kvs), err)
+	}
+	if err := s.DeleteBatch(ns, []byte("batch@example.com")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(ns, []byte("batch@example.com")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file@example.com"), []byte("f"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ImportDir(dir, "users"); err != nil {
+		t.Error(err)
+	}
+	get("file@example.com", "f")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("@example.com")) {
+		t.Error("expected no plaintext key in the file")
+	}
+}
+
+func TestHashedKeysMode(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithHashedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrHashedKeys) {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	if _, err := Open(path, WithReadOnly()); !errors.Is(err, ErrHashedKeys) {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	s, err = Open(path, WithHashedKeys(), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// files with namespaces but no recorded mode hold plain keys
+	plain, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(plain)
+	db, err := bolt.Open(plain, _fileMode, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("users"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, err := Open(plain, WithHashedKeys()); !errors.Is(err, ErrHashedKeys) {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+	s, err = Open(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+}
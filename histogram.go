@@ -0,0 +1,150 @@
+package gostore
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _reservoirSize = 1024
+
+// SizeHistogram is the distribution of the value sizes of a namespace,
+// computed from a uniform sample of at most 1024 values.
+type SizeHistogram struct {
+	// Values is the number of values the sample was drawn from.
+	Values int64
+	// Samples is the number of values in the sample.
+	Samples int
+	// Min, Max and the percentiles are value sizes in bytes.
+	Min, Max      int
+	P50, P90, P99 int
+	// Buckets count the samples by size, in powers of two up to Max.
+	Buckets []SizeBucket
+}
+
+// SizeBucket counts the samples larger than half of UpTo and at most UpTo
+// bytes, or of zero or one byte for the first bucket.
+type SizeBucket struct {
+	UpTo  int
+	Count int
+}
+
+// reservoir is a uniform sample of value sizes
+type reservoir struct {
+	seen  int64
+	sizes []int
+}
+
+func (r *reservoir) add(size int) {
+	r.seen++
+	if len(r.sizes) < _reservoirSize {
+		r.sizes = append(r.sizes, size)
+	} else if i := rand.Int64N(r.seen); i < _reservoirSize {
+		r.sizes[i] = size
+	}
+}
+
+// sizeSamples holds the value sizes sampled from reads by namespace
+type sizeSamples struct {
+	mu         sync.Mutex
+	namespaces map[string]*reservoir
+}
+
+// WithSizeSampling samples the sizes of the values read by Get, for
+// SizeHistogram to report the distribution seen by reads without scanning
+// the namespace.
+func WithSizeSampling() Option {
+	return func(o *option) error {
+		o.sizeSampling = true
+		return nil
+	}
+}
+
+// sampleSize records a value read from a namespace
+func (s *Store) sampleSize(namespace []byte, size int) {
+	if !s.opt.Load().sizeSampling {
+		return
+	}
+	s.sizes.mu.Lock()
+	defer s.sizes.mu.Unlock()
+	r, ok := s.sizes.namespaces[string(namespace)]
+	if !ok {
+		if s.sizes.namespaces == nil {
+			s.sizes.namespaces = make(map[string]*reservoir)
+		}
+		r = &reservoir{}
+		s.sizes.namespaces[string(namespace)] = r
+	}
+	r.add(size)
+}
+
+// SizeHistogram returns the distribution of the value sizes of a
+// namespace. With WithSizeSampling it is that of the values read so far,
+// once there are any; otherwise it is computed by ScanSizeHistogram.
+func (s *Store) SizeHistogram(namespace []byte) (SizeHistogram, error) {
+	if s.opt.Load().sizeSampling {
+		s.sizes.mu.Lock()
+		r, ok := s.sizes.namespaces[string(namespace)]
+		var h SizeHistogram
+		if ok {
+			h = r.histogram()
+		}
+		s.sizes.mu.Unlock()
+		if ok {
+			return h, nil
+		}
+	}
+	return s.ScanSizeHistogram(namespace)
+}
+
+// ScanSizeHistogram returns the distribution of the value sizes of the
+// live records of a namespace, sampled in a single pass over it.
+func (s *Store) ScanSizeHistogram(namespace []byte) (SizeHistogram, error) {
+	var r reservoir
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+		now := time.Now()
+		return bucket.ForEach(func(_, v []byte) error {
+			if expire, ok := expireOf(v); ok && (expire.IsZero() || expire.After(now)) {
+				r.add(len(v) - 12)
+			}
+			return nil
+		})
+	})
+	return r.histogram(), err
+}
+
+func (r *reservoir) histogram() SizeHistogram {
+	h := SizeHistogram{Values: r.seen, Samples: len(r.sizes)}
+	if len(r.sizes) == 0 {
+		return h
+	}
+	sizes := slices.Clone(r.sizes)
+	slices.Sort(sizes)
+	at := func(q float64) int { return sizes[int(q*float64(len(sizes)-1))] }
+	h.Min, h.Max = sizes[0], sizes[len(sizes)-1]
+	h.P50, h.P90, h.P99 = at(0.5), at(0.9), at(0.99)
+	h.Buckets = make([]SizeBucket, bucketOf(h.Max)+1)
+	for i := range h.Buckets {
+		h.Buckets[i].UpTo = 1 << i
+	}
+	for _, size := range sizes {
+		h.Buckets[bucketOf(size)].Count++
+	}
+	return h
+}
+
+// bucketOf returns the index of the power-of-two bucket of size
+func bucketOf(size int) int {
+	if size <= 1 {
+		return 0
+	}
+	return bits.Len(uint(size - 1))
+}
@@ -0,0 +1,62 @@
+package gostore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithSizeSampling())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 1; i <= 2000; i++ {
+		if err := s.Put("values", []byte(fmt.Sprint(i)), bytes.Repeat([]byte("x"), i%100+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// without reads the namespace is scanned
+	h, err := s.SizeHistogram([]byte("values"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Values != 2000 || h.Samples != _reservoirSize || h.Min < 1 || h.Max > 100 || h.P50 > h.P90 || h.P90 > h.P99 {
+		t.Errorf("expected a sample of 2000 values got %+v", h)
+	}
+	if len(h.Buckets) != 8 || h.Buckets[7].UpTo != 128 {
+		t.Errorf("expected buckets up to 128 got %+v", h.Buckets)
+	}
+	total := 0
+	for _, b := range h.Buckets {
+		total += b.Count
+	}
+	if total != h.Samples {
+		t.Errorf("expected %d samples in buckets got %d", h.Samples, total)
+	}
+
+	for _, key := range []string{"9", "99", "9"} {
+		if _, err := s.Get([]byte("values"), []byte(key)); err != nil {
+			t.Error(err)
+		}
+	}
+	h, err = s.SizeHistogram([]byte("values"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Values != 3 || h.Min != 10 || h.Max != 100 || h.P50 != 10 {
+		t.Errorf("expected the sizes read got %+v", h)
+	}
+	h, err = s.ScanSizeHistogram([]byte("missing"))
+	if err != nil || h.Samples != 0 || h.Buckets != nil {
+		t.Errorf("expected an empty histogram got %+v, %v", h, err)
+	}
+}
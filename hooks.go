@@ -0,0 +1,51 @@
+package gostore
+
+import (
+	"strings"
+	"time"
+)
+
+// Hooks are called around the operations of the store, for logging,
+// metrics or validation. Nil hooks are skipped. Hooks run on the goroutine
+// of the operation, so they should be quick. The keys they see are the
+// stored ones, hashed when the store was opened WithHashedKeys.
+type Hooks struct {
+	// BeforePut runs before every write of a record: Put and PutWithTTL,
+	// CAS, PutNX, Incr, the batch and Tx writes, PutSeq, PutIdempotent and
+	// the rest. An error fails the write. It runs again when a transaction
+	// is retried.
+	BeforePut func(namespace, key, value []byte) error
+	// AfterPut runs after those writes, with their error, once the
+	// transaction has committed. Writes of a transaction rolled back after
+	// they succeeded are not reported.
+	AfterPut func(namespace, key, value []byte, err error)
+	// AfterGet runs after Get, with its result.
+	AfterGet func(namespace, key, value []byte, err error)
+	// OnDelete runs once the deletion of a key has committed, including
+	// the deletions of expired keys by the garbage collector and of the
+	// keys evicted by QuotaEvictOldest.
+	OnDelete func(namespace, key []byte)
+	// OnExpire runs for the keys deleted by the garbage collector and the
+	// keys reads found expired, as reported by ExpiredKeys.
+	OnExpire func(namespace, key []byte, expire time.Time)
+	// OnEvict runs for the keys the LRU cache evicts to make room.
+	OnEvict func(namespace, key []byte)
+}
+
+// WithHooks sets the hooks called around operations
+func WithHooks(h Hooks) Option {
+	return func(o *option) error {
+		o.hooks = h
+		return nil
+	}
+}
+
+// splitCacheKey returns the namespace and key of a cache key, see cacheKey
+func splitCacheKey(k string) (namespace, key []byte) {
+	if rest, ok := strings.CutPrefix(k, "\x00"); ok {
+		if ns, key, ok := strings.Cut(rest, "\x00"); ok {
+			return []byte(ns), []byte(key)
+		}
+	}
+	return []byte(_defaultBucket), []byte(k)
+}
@@ -0,0 +1,180 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+
+	var (
+		mu     sync.Mutex
+		calls  []string
+		errBad = errors.New("rejected")
+	)
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+	s, err := Open(path, WithMaxCacheSize(1), WithCacheNamespaces("ns"), WithHooks(Hooks{
+		BeforePut: func(namespace, key, value []byte) error {
+			if string(value) == "bad" {
+				return errBad
+			}
+			record("before " + string(key))
+			return nil
+		},
+		AfterPut: func(namespace, key, value []byte, err error) {
+			record("after " + string(key))
+		},
+		AfterGet: func(namespace, key, value []byte, err error) {
+			record("get " + string(key) + "=" + string(value))
+		},
+		OnExpire: func(namespace, key []byte, expire time.Time) {
+			record("expire " + string(key))
+		},
+		OnEvict: func(namespace, key []byte) {
+			record("evict " + string(namespace) + "/" + string(key))
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("ns")
+	if err := s.Put(string(ns), []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("b"), []byte("bad")); !errors.Is(err, errBad) {
+		t.Errorf("expected error %s, got %v", errBad, err)
+	}
+	if err := s.PutWithTTL(ns, []byte("c"), []byte("3"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err := s.Get(ns, []byte("x")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	if _, err := s.GC(); err != nil {
+		t.Error(err)
+	}
+
+	expected := []string{
+		"before a", "after a",
+		"before c", "after c", "evict ns/a",
+		"get x=",
+		"expire c",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, calls)
+			break
+		}
+	}
+}
+
+func TestHooksEveryWrite(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+
+	var (
+		mu     sync.Mutex
+		calls  []string
+		errBad = errors.New("rejected")
+	)
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+	s, err := Open(path, WithHooks(Hooks{
+		BeforePut: func(namespace, key, value []byte) error {
+			if string(key) == "bad" {
+				return errBad
+			}
+			record("before " + string(key) + "=" + string(value))
+			return nil
+		},
+		AfterPut: func(namespace, key, value []byte, err error) {
+			record("after " + string(key))
+		},
+		OnDelete: func(namespace, key []byte) {
+			record("delete " + string(key))
+		},
+	}), WithNamespaceQuota("q", Quota{MaxKeys: 1, Policy: QuotaEvictOldest}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("ns")
+	if _, err := s.Incr(ns, []byte("n"), 1); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.PutNX(ns, []byte("b"), []byte("1"), 0); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.CAS(ns, []byte("b"), []byte("1"), []byte("2")); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutMulti([]Entry{{Namespace: ns, Key: []byte("c"), Value: []byte("3")}}); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete(string(ns), []byte("c")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Incr(ns, []byte("bad"), 1); !errors.Is(err, errBad) {
+		t.Errorf("expected error %s, got %v", errBad, err)
+	}
+	if err := s.PutMulti([]Entry{{Namespace: ns, Key: []byte("bad"), Value: []byte("x")}}); !errors.Is(err, errBad) {
+		t.Errorf("expected error %s, got %v", errBad, err)
+	}
+	if _, err := s.Get(ns, []byte("bad")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	// quota evictions are deletions too
+	for _, key := range []string{"x", "y"} {
+		if err := s.Put("q", []byte(key), []byte("v")); err != nil {
+			t.Error(err)
+		}
+	}
+
+	expected := []string{
+		"before n=\x01\x00\x00\x00\x00\x00\x00\x00", "after n",
+		"before b=1", "after b",
+		"before b=2", "after b",
+		"before c=3", "after c",
+		"delete c",
+		"before x=v", "after x",
+		"before y=v", "delete x", "after y",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, calls)
+			break
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrBadToken is returned by ParseToken for a token that is malformed, not
+// signed with the secret or expired.
+var ErrBadToken = errors.New("bad token")
+
+// Token is a capability granting access to the keys of a namespace that
+// start with a prefix, until it expires. Clients send it signed, as
+// returned by SignToken, in an "Authorization: Bearer" header.
+type Token struct {
+	// Namespace is the namespace granted, empty for every namespace.
+	Namespace string `json:"ns,omitempty"`
+	// Prefix is the prefix of the keys granted, empty for every key.
+	Prefix []byte `json:"prefix,omitempty"`
+	// Read grants GET and _mget, Write PUT, DELETE, _mput and _mdelete.
+	Read   bool      `json:"r,omitempty"`
+	Write  bool      `json:"w,omitempty"`
+	Expire time.Time `json:"exp"`
+}
+
+// WithTokenSecret makes every request carry a Token signed with secret and
+// limits it to what the token grants. Requests without a valid token get
+// 401, requests beyond it 403.
+func WithTokenSecret(secret []byte) Option {
+	return func(o *option) error {
+		if len(secret) == 0 {
+			return errors.New("empty token secret")
+		}
+		o.tokenSecret = secret
+		return nil
+	}
+}
+
+// WithClientCertNamespaces requires a verified TLS client certificate and
+// limits each client to the namespaces listed for the common name of its
+// certificate, "*" allowing every namespace. Clients without one get 401,
+// requests to other namespaces 403. Serve the handler with a TLS config
+// verifying client certificates, such as ClientTLSConfig returns.
+func WithClientCertNamespaces(allow map[string][]string) Option {
+	return func(o *option) error {
+		o.clientNamespaces = allow
+		return nil
+	}
+}
+
+// ClientTLSConfig returns a server TLS config requiring client
+// certificates signed by clientCAs, for WithClientCertNamespaces
+func ClientTLSConfig(certificate tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// SignToken returns t signed with secret: its JSON encoding and an
+// HMAC-SHA256 of it, both unpadded URL-safe base64, joined by a dot.
+func SignToken(secret []byte, t Token) (string, error) {
+	if t.Expire.IsZero() {
+		return "", errors.New("token without expiry")
+	}
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(tokenMAC(secret, payload)), nil
+}
+
+// ParseToken verifies a token signed by SignToken with secret and returns
+// it, failing with ErrBadToken if it is invalid or expired.
+func ParseToken(secret []byte, s string) (Token, error) {
+	var t Token
+	payload64, mac64, ok := strings.Cut(s, ".")
+	if !ok {
+		return t, ErrBadToken
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(payload64)
+	if err != nil {
+		return t, ErrBadToken
+	}
+	mac, err := enc.DecodeString(mac64)
+	if err != nil || !hmac.Equal(mac, tokenMAC(secret, payload)) {
+		return t, ErrBadToken
+	}
+	if err := json.Unmarshal(payload, &t); err != nil || !time.Now().Before(t.Expire) {
+		return Token{}, ErrBadToken
+	}
+	return t, nil
+}
+
+func tokenMAC(secret, payload []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+// grant is a limit on what a request may do
+type grant interface {
+	// allows reports whether the request may read, or write, the keys of
+	// namespace starting with key
+	allows(namespace string, key []byte, write bool) bool
+	// sees reports whether the request may use namespace at all
+	sees(namespace string) bool
+}
+
+func (t Token) allows(namespace string, key []byte, write bool) bool {
+	if write && !t.Write || !write && !t.Read {
+		return false
+	}
+	return t.sees(namespace) && bytes.HasPrefix(key, t.Prefix)
+}
+
+func (t Token) sees(namespace string) bool {
+	return t.Namespace == "" || t.Namespace == namespace
+}
+
+// namespaceGrant allows the namespaces of a client certificate
+type namespaceGrant []string
+
+func (g namespaceGrant) allows(namespace string, key []byte, write bool) bool {
+	return g.sees(namespace)
+}
+
+func (g namespaceGrant) sees(namespace string) bool {
+	for _, ns := range g {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// clientName returns the common name of the verified client certificate
+// of r, false if it has none
+func clientName(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+type grantsKey struct{}
+
+// authenticate returns the request with the grants of its credentials in
+// its context, or replies with 401 and returns nil
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) *http.Request {
+	var grants []grant
+	if h.opt.tokenSecret != nil {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		t, err := ParseToken(h.opt.tokenSecret, bearer)
+		if !ok || err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or bad token", http.StatusUnauthorized)
+			return nil
+		}
+		grants = append(grants, t)
+	}
+	if h.opt.clientNamespaces != nil {
+		name, ok := clientName(r)
+		if !ok {
+			http.Error(w, "missing client certificate", http.StatusUnauthorized)
+			return nil
+		}
+		grants = append(grants, namespaceGrant(h.opt.clientNamespaces[name]))
+	}
+	if grants == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), grantsKey{}, grants))
+}
+
+// allowed reports whether every grant of r allows reading, or writing, keys
+// of namespace, replying with 403 if not
+func allowed(w http.ResponseWriter, r *http.Request, namespace string, write bool, keys ...[]byte) bool {
+	grants, _ := r.Context().Value(grantsKey{}).([]grant)
+	for _, g := range grants {
+		for _, k := range keys {
+			if !g.allows(namespace, k, write) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// visible reports whether every grant of r lets it see namespace
+func visible(r *http.Request, namespace string) bool {
+	grants, _ := r.Context().Value(grantsKey{}).([]grant)
+	for _, g := range grants {
+		if !g.sees(namespace) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package httpd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/millken/gostore"
+)
+
+func TestToken(t *testing.T) {
+	secret := []byte("secret")
+	tok, err := SignToken(secret, Token{Namespace: "users", Read: true, Expire: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ParseToken(secret, tok); err != nil || got.Namespace != "users" || !got.Read || got.Write {
+		t.Errorf("expected the signed token got %+v, %v", got, err)
+	}
+	if _, err := ParseToken([]byte("other"), tok); !errors.Is(err, ErrBadToken) {
+		t.Errorf("expected error %s got %v", ErrBadToken, err)
+	}
+	payload, mac, _ := strings.Cut(tok, ".")
+	if _, err := ParseToken(secret, payload+"x."+mac); !errors.Is(err, ErrBadToken) {
+		t.Errorf("expected error %s got %v", ErrBadToken, err)
+	}
+	expired, err := SignToken(secret, Token{Read: true, Expire: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(secret, expired); !errors.Is(err, ErrBadToken) {
+		t.Errorf("expected error %s got %v", ErrBadToken, err)
+	}
+	if _, err := SignToken(secret, Token{Read: true}); err == nil {
+		t.Error("expected an error for a token without expiry")
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	secret := []byte("secret")
+	h, err := New(store, WithTokenSecret(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"users", "orders"} {
+		if err := store.Put(ns, []byte("ann"), []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sign := func(tok Token) string {
+		tok.Expire = time.Now().Add(time.Hour)
+		s, err := SignToken(secret, tok)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	reader := sign(Token{Namespace: "users", Prefix: []byte("a"), Read: true})
+	writer := sign(Token{Namespace: "users", Write: true})
+
+	tests := []struct {
+		method, path, body, token string
+		status                    int
+	}{
+		{"GET", "/v1/users/ann", "", "", http.StatusUnauthorized},
+		{"GET", "/v1/users/ann", "", "bad", http.StatusUnauthorized},
+		{"GET", "/v1/users/ann", "", reader, http.StatusOK},
+		{"GET", "/v1/users/bob", "", reader, http.StatusForbidden},
+		{"GET", "/v1/orders/ann", "", reader, http.StatusForbidden},
+		{"PUT", "/v1/users/ann", "2", reader, http.StatusForbidden},
+		{"GET", "/v1/users", "", reader, http.StatusForbidden},
+		{"GET", "/v1/users?prefix=YW4", "", reader, http.StatusOK},
+		{"POST", "/v1/users/_mget", `{"keys":["YW5u","Ym9i"]}`, reader, http.StatusForbidden},
+		{"PUT", "/v1/users/bob", "2", writer, http.StatusNoContent},
+		{"GET", "/v1/users/bob", "", writer, http.StatusForbidden},
+		{"POST", "/v1/users/_mdelete", `{"keys":["Ym9i"]}`, writer, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("expected status %d for %s %s got %d %s", tt.status, tt.method, tt.path, rec.Code, rec.Body)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/v1/", nil)
+	req.Header.Set("Authorization", "Bearer "+reader)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if body := rec.Body.String(); !strings.Contains(body, `"users"`) || strings.Contains(body, `"orders"`) {
+		t.Errorf("expected only users listed got %s", body)
+	}
+}
+
+func TestHandlerClientCert(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store, WithClientCertNamespaces(map[string][]string{
+		"billing": {"invoices"},
+		"admin":   {"*"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCert := func(req *http.Request, name string) *http.Request {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return req
+	}
+
+	tests := []struct {
+		method, path, cn string
+		status           int
+	}{
+		{"PUT", "/v1/invoices/1", "", http.StatusUnauthorized},
+		{"PUT", "/v1/invoices/1", "billing", http.StatusNoContent},
+		{"GET", "/v1/invoices/1", "billing", http.StatusOK},
+		{"GET", "/v1/users/1", "billing", http.StatusForbidden},
+		{"GET", "/v1/invoices/1", "unknown", http.StatusForbidden},
+		{"PUT", "/v1/users/1", "admin", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("v"))
+		if tt.cn != "" {
+			req = withCert(req, tt.cn)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("expected status %d for %s %s as %q got %d %s", tt.status, tt.method, tt.path, tt.cn, rec.Code, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, withCert(httptest.NewRequest("GET", "/v1/", nil), "billing"))
+	if body := rec.Body.String(); !strings.Contains(body, `"invoices"`) || strings.Contains(body, `"users"`) {
+		t.Errorf("expected only invoices listed got %s", body)
+	}
+}
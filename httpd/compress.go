@@ -0,0 +1,102 @@
+package httpd
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// _minGzipSize is the smallest response worth compressing
+const _minGzipSize = 1 << 10
+
+// WithMaxResponseSize limits the size of response bodies before
+// compression. Larger responses fail with 500 and a JSON error, so clients
+// fetch fewer keys or page with a smaller limit instead. Unlimited by
+// default.
+func WithMaxResponseSize(n int64) Option {
+	return func(o *option) error {
+		if n <= 0 {
+			return errors.New("response size must be positive")
+		}
+		o.maxResponseSize = n
+		return nil
+	}
+}
+
+// gzipBody is a gzip request body, closing the compressed body with it
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+// Close implements io.Closer
+func (b gzipBody) Close() error {
+	return errors.Join(b.Reader.Close(), b.body.Close())
+}
+
+// decodeBody replaces a gzip request body with its decoded content, limited
+// to the body size like a plain one, replying with an error if it cannot.
+// gzip is the only encoding supported.
+func (h *Handler) decodeBody(w http.ResponseWriter, r *http.Request) bool {
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return true
+	case "gzip":
+	default:
+		w.Header().Set("Accept-Encoding", "gzip")
+		http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return false
+	}
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		writeBodyError(w, err)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, gzipBody{Reader: zr, body: r.Body}, h.opt.maxBodySize)
+	r.Header.Del("Content-Encoding")
+	r.ContentLength = -1
+	return true
+}
+
+// acceptsGzip reports whether the client accepts gzip responses
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "gzip" && name != "*" {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+		if v, err := strconv.ParseFloat(q, 64); err == nil && v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// write replies with body, compressed if the client accepts gzip and it is
+// large enough, or with an error if it is larger than the response size
+// limit
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	if h.opt.maxResponseSize > 0 && int64(len(body)) > h.opt.maxResponseSize {
+		writeError(w, fmt.Errorf("response exceeds %d bytes", h.opt.maxResponseSize))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if len(body) < _minGzipSize || !acceptsGzip(r) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body)
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	zw := gzip.NewWriter(w)
+	_, _ = zw.Write(body)
+	_ = zw.Close()
+}
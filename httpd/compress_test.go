@@ -0,0 +1,146 @@
+package httpd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/millken/gostore"
+)
+
+func gzipped(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, expected := range map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"br, GZIP":            true,
+		"deflate, gzip;q=0.5": true,
+		"gzip;q=0":            false,
+		"*":                   true,
+		"identity":            false,
+	} {
+		r := httptest.NewRequest("GET", "/v1/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptsGzip(r); got != expected {
+			t.Errorf("expected %v for %q got %v", expected, header, got)
+		}
+	}
+}
+
+func TestHandlerCompression(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store, WithMaxBodySize(4096), WithMaxResponseSize(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method, target string, body []byte, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	value := bytes.Repeat([]byte("gostore "), 256)
+	if rec := serve("PUT", "/v1/ns/k", gzipped(t, value), "Content-Encoding", "gzip"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d got %d %s", http.StatusNoContent, rec.Code, rec.Body)
+	}
+	if v, err := store.Get([]byte("ns"), []byte("k")); err != nil || !bytes.Equal(v, value) {
+		t.Errorf("expected the decoded value got %d bytes, %v", len(v), err)
+	}
+
+	rec := serve("GET", "/v1/ns/k", nil, "Accept-Encoding", "gzip")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response got %d %v", rec.Code, rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := io.ReadAll(zr); err != nil || !bytes.Equal(v, value) {
+		t.Errorf("expected the value got %d bytes, %v", len(v), err)
+	}
+	if rec := serve("GET", "/v1/ns/k", nil); rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), value) {
+		t.Errorf("expected a plain response got %v", rec.Header())
+	}
+	if rec := serve("GET", "/v1/ns", nil, "Accept-Encoding", "gzip"); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected a small response not compressed got %v", rec.Header())
+	}
+
+	// the body limit applies to the decoded body
+	big := bytes.Repeat([]byte{0}, 8192)
+	if rec := serve("PUT", "/v1/ns/big", gzipped(t, big), "Content-Encoding", "gzip"); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+	if rec := serve("PUT", "/v1/ns/bad", []byte("not gzip"), "Content-Encoding", "gzip"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := serve("PUT", "/v1/ns/zstd", value, "Content-Encoding", "zstd"); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status %d got %d", http.StatusUnsupportedMediaType, rec.Code)
+	}
+
+	if err := store.Put("ns", []byte("big"), big); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve("GET", "/v1/ns/big", nil, "Accept-Encoding", "gzip"); rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"error":"response exceeds 4096 bytes"`) {
+		t.Errorf("expected status %d got %d %s", http.StatusInternalServerError, rec.Code, rec.Body)
+	}
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestHandlerCompressionAdmission(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store, WithTokenSecret([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := &countingReader{r: bytes.NewReader(gzipped(t, []byte("value")))}
+	req := httptest.NewRequest("PUT", "/v1/ns/k", body)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if body.n != 0 {
+		t.Errorf("expected the body of an unauthenticated request unread, got %d bytes read", body.n)
+	}
+}
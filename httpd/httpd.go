@@ -0,0 +1,423 @@
+// Package httpd serves a gostore.Store over HTTP as an http.Handler, to be
+// mounted into an existing mux:
+//
+//	h, err := httpd.New(store)
+//	mux.Handle("/v1/", h)
+//
+// The routes are:
+//
+//	GET    /v1/                      namespaces
+//	GET    /v1/{namespace}           keys, paged with ?prefix=, ?after=, ?cursor= and ?limit=
+//	GET    /v1/{namespace}/{key}     value
+//	PUT    /v1/{namespace}/{key}     set the value to the body
+//	DELETE /v1/{namespace}/{key}     delete
+//	POST   /v1/{namespace}/_mget     values of {"keys": [...]}
+//	POST   /v1/{namespace}/_mput     set {"records": [{"key", "value", "ttl"}]}
+//	POST   /v1/{namespace}/_mdelete  delete {"keys": [...]}
+//
+// The X-Gostore-TTL header carries the TTL in seconds of PUT and the time
+// left of GET. Keys in JSON bodies and the prefix, after and cursor
+// parameters are unpadded URL-safe base64, so binary keys page reliably;
+// values in JSON bodies are base64, as encoding/json encodes byte slices.
+//
+// Keys are listed in binary order. A page starts at the first key of the
+// prefix after the key given by after, or at the cursor, the continuation
+// token returned as next by the previous page; next is null after the
+// last page.
+//
+// WithTokenSecret limits every request to what its capability Token
+// grants, WithClientCertNamespaces to the namespaces of the TLS client
+// certificate it was sent with. WithRateLimit and WithMaxInFlight answer
+// 429 to the requests beyond their limits.
+//
+// Request bodies sent with Content-Encoding: gzip are decoded, and
+// responses are gzipped for clients sending Accept-Encoding: gzip; gzip is
+// the only encoding supported, others are refused with 415. Bodies beyond
+// WithMaxBodySize are refused with 413, and responses beyond
+// WithMaxResponseSize fail with 500.
+package httpd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/millken/gostore"
+)
+
+// TTLHeader is the header carrying TTLs in seconds
+const TTLHeader = "X-Gostore-TTL"
+
+const _defaultMaxBodySize = 32 << 20
+
+// Option configures a Handler
+type Option func(*option) error
+
+type option struct {
+	maxBodySize      int64
+	maxResponseSize  int64
+	tokenSecret      []byte
+	clientNamespaces map[string][]string
+	rate             float64
+	burst            int
+	maxInFlight      int
+}
+
+// WithMaxBodySize limits the size of request bodies, 32 MiB by default.
+// The limit applies to gzip bodies once decoded.
+func WithMaxBodySize(n int64) Option {
+	return func(o *option) error {
+		if n <= 0 {
+			return errors.New("body size must be positive")
+		}
+		o.maxBodySize = n
+		return nil
+	}
+}
+
+// Handler serves a store over HTTP
+type Handler struct {
+	store *gostore.Store
+	opt   option
+	mux   *http.ServeMux
+	// limiter and inFlight are nil without rate and in-flight limits
+	limiter  *rateLimiter
+	inFlight chan struct{}
+}
+
+// New returns a handler of store
+func New(store *gostore.Store, opts ...Option) (*Handler, error) {
+	opt := option{maxBodySize: _defaultMaxBodySize}
+	for _, o := range opts {
+		if err := o(&opt); err != nil {
+			return nil, err
+		}
+	}
+	h := &Handler{store: store, opt: opt, mux: http.NewServeMux()}
+	if opt.rate > 0 {
+		h.limiter = newRateLimiter(opt.rate, opt.burst)
+	}
+	if opt.maxInFlight > 0 {
+		h.inFlight = make(chan struct{}, opt.maxInFlight)
+	}
+	h.mux.HandleFunc("GET /v1", h.namespaces)
+	h.mux.HandleFunc("GET /v1/{$}", h.namespaces)
+	h.mux.HandleFunc("GET /v1/{namespace}", h.namespaced(h.keys))
+	h.mux.HandleFunc("GET /v1/{namespace}/{key}", h.namespaced(h.get))
+	h.mux.HandleFunc("PUT /v1/{namespace}/{key}", h.namespaced(h.put))
+	h.mux.HandleFunc("DELETE /v1/{namespace}/{key}", h.namespaced(h.delete))
+	h.mux.HandleFunc("POST /v1/{namespace}/_mget", h.namespaced(h.mget))
+	h.mux.HandleFunc("POST /v1/{namespace}/_mput", h.namespaced(h.mput))
+	h.mux.HandleFunc("POST /v1/{namespace}/_mdelete", h.namespaced(h.mdelete))
+	return h, nil
+}
+
+// namespaced refuses the requests for internal namespaces before calling
+// fn
+func (h *Handler) namespaced(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if gostore.IsInternalNamespace(r.PathValue("namespace")) {
+			http.Error(w, "internal namespace", http.StatusBadRequest)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r = h.authenticate(w, r); r == nil {
+		return
+	}
+	done := h.limit(w, r)
+	if done == nil {
+		return
+	}
+	defer done()
+	// only admitted requests get their body read
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, h.opt.maxBodySize)
+		if !h.decodeBody(w, r) {
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) namespaces(w http.ResponseWriter, r *http.Request) {
+	names, err := h.store.Namespaces()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	visibleNames := []string{}
+	for _, name := range names {
+		if visible(r, name) {
+			visibleNames = append(visibleNames, name)
+		}
+	}
+	h.writeJSON(w, r, map[string]any{"namespaces": visibleNames})
+}
+
+func (h *Handler) keys(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+	}
+	var prefix, after, cursor key
+	if err := prefix.UnmarshalText([]byte(r.URL.Query().Get("prefix"))); err != nil {
+		http.Error(w, "bad prefix", http.StatusBadRequest)
+		return
+	}
+	if err := after.UnmarshalText([]byte(r.URL.Query().Get("after"))); err != nil {
+		http.Error(w, "bad after", http.StatusBadRequest)
+		return
+	}
+	if err := cursor.UnmarshalText([]byte(r.URL.Query().Get("cursor"))); err != nil {
+		http.Error(w, "bad cursor", http.StatusBadRequest)
+		return
+	}
+	if !allowed(w, r, r.PathValue("namespace"), false, prefix) {
+		return
+	}
+	if r.URL.Query().Has("after") {
+		// the smallest key greater than after
+		if start := append(after, 0); bytes.Compare(cursor, start) < 0 {
+			cursor = start
+		}
+	}
+	if bytes.Compare(cursor, prefix) < 0 {
+		cursor = prefix
+	}
+	page, next, err := h.store.Keys([]byte(r.PathValue("namespace")), cursor, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	keys := make([]key, 0, len(page))
+	for _, k := range page {
+		if !bytes.HasPrefix(k, prefix) {
+			// keys are sorted, so the later ones do not match either
+			next = nil
+			break
+		}
+		keys = append(keys, k)
+	}
+	if !bytes.HasPrefix(next, prefix) {
+		next = nil
+	}
+	h.writeJSON(w, r, map[string]any{"keys": keys, "next": key(next)})
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	if !allowed(w, r, r.PathValue("namespace"), false, []byte(r.PathValue("key"))) {
+		return
+	}
+	v, remaining, err := h.store.GetTTL([]byte(r.PathValue("namespace")), []byte(r.PathValue("key")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if remaining > 0 {
+		w.Header().Set(TTLHeader, strconv.FormatInt(int64((remaining+time.Second-1)/time.Second), 10))
+	}
+	h.write(w, r, "application/octet-stream", v)
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request) {
+	if !allowed(w, r, r.PathValue("namespace"), true, []byte(r.PathValue("key"))) {
+		return
+	}
+	var ttl int64
+	if t := r.Header.Get(TTLHeader); t != "" {
+		var err error
+		if ttl, err = strconv.ParseInt(t, 10, 64); err != nil || ttl < 0 {
+			http.Error(w, "bad "+TTLHeader, http.StatusBadRequest)
+			return
+		}
+	}
+	value, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if err := h.store.PutWithTTL([]byte(r.PathValue("namespace")), []byte(r.PathValue("key")), value, ttl); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	if !allowed(w, r, r.PathValue("namespace"), true, []byte(r.PathValue("key"))) {
+		return
+	}
+	if err := h.store.Delete(r.PathValue("namespace"), []byte(r.PathValue("key"))); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// key is a key encoded in JSON and query parameters as unpadded URL-safe
+// base64
+type key []byte
+
+// MarshalText implements encoding.TextMarshaler
+func (k key) MarshalText() ([]byte, error) {
+	return base64.RawURLEncoding.AppendEncode(nil, k), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (k *key) UnmarshalText(text []byte) error {
+	b, err := base64.RawURLEncoding.AppendDecode(nil, text)
+	*k = b
+	return err
+}
+
+type keysRequest struct {
+	Keys []key `json:"keys"`
+}
+
+type record struct {
+	Key   key    `json:"key"`
+	Value []byte `json:"value"`
+	TTL   int64  `json:"ttl,omitempty"`
+}
+
+// mget replies with the values of the keys that exist
+func (h *Handler) mget(w http.ResponseWriter, r *http.Request) {
+	var req keysRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	keys := make([][]byte, len(req.Keys))
+	for i, k := range req.Keys {
+		keys[i] = k
+	}
+	if !allowed(w, r, r.PathValue("namespace"), false, keys...) {
+		return
+	}
+	values, err := h.store.MGet([]byte(r.PathValue("namespace")), keys...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	found := make(map[string][]byte, len(values))
+	for i, v := range values {
+		if v != nil {
+			text, _ := req.Keys[i].MarshalText()
+			found[string(text)] = v
+		}
+	}
+	h.writeJSON(w, r, map[string]any{"values": found})
+}
+
+// mput writes the records in one transaction
+func (h *Handler) mput(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Records []record `json:"records"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	namespace := []byte(r.PathValue("namespace"))
+	entries := make([]gostore.Entry, len(req.Records))
+	for i, rec := range req.Records {
+		if rec.TTL < 0 {
+			http.Error(w, "bad ttl", http.StatusBadRequest)
+			return
+		}
+		if !allowed(w, r, string(namespace), true, rec.Key) {
+			return
+		}
+		entries[i] = gostore.Entry{Namespace: namespace, Key: rec.Key, Value: rec.Value, TTL: rec.TTL}
+	}
+	if err := h.store.PutMulti(entries); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) mdelete(w http.ResponseWriter, r *http.Request) {
+	var req keysRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	keys := make([][]byte, len(req.Keys))
+	for i, k := range req.Keys {
+		keys[i] = k
+	}
+	if !allowed(w, r, r.PathValue("namespace"), true, keys...) {
+		return
+	}
+	if err := h.store.DeleteBatch([]byte(r.PathValue("namespace")), keys...); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readJSON decodes the request body into v, replying with an error if it
+// cannot
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeBodyError(w, err)
+		return false
+	}
+	return true
+}
+
+// writeBodyError replies with the error of reading the request body
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	} else {
+		http.Error(w, "bad request body: "+err.Error(), http.StatusBadRequest)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.write(w, r, "application/json", append(b, '\n'))
+}
+
+// writeError replies with the status of the error code of err
+func writeError(w http.ResponseWriter, err error) {
+	var tooBig *http.MaxBytesError
+	status := http.StatusInternalServerError
+	switch code := gostore.Code(err); {
+	case errors.As(err, &tooBig):
+		status = http.StatusRequestEntityTooLarge
+	case code == gostore.CodeInvalid:
+		status = http.StatusBadRequest
+	case code == gostore.CodeNotFound, code == gostore.CodeExpired:
+		status = http.StatusNotFound
+	case code == gostore.CodeReadOnly:
+		status = http.StatusForbidden
+	case code == gostore.CodeQuota:
+		status = http.StatusInsufficientStorage
+	case code == gostore.CodeConflict:
+		status = http.StatusConflict
+	case code == gostore.CodeTimeout, code == gostore.CodeClosed, code == gostore.CodeUnavailable:
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "code": gostore.Code(err).String()})
+}
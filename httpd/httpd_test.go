@@ -0,0 +1,208 @@
+package httpd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/millken/gostore"
+)
+
+func TestHandler(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store, WithMaxBodySize(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", h)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(method, path, body string, header ...string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	if resp, _ := do("PUT", "/v1/users/ann", "1"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp, _ := do("PUT", "/v1/users/bob", "2", TTLHeader, "60"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp, body := do("GET", "/v1/users/ann", ""); resp.StatusCode != http.StatusOK || body != "1" || resp.Header.Get(TTLHeader) != "" {
+		t.Errorf("expected 1 got %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := do("GET", "/v1/users/bob", ""); resp.Header.Get(TTLHeader) != "60" {
+		t.Errorf("expected ttl 60 got %s", resp.Header.Get(TTLHeader))
+	}
+	if resp, body := do("GET", "/v1/users/carol", ""); resp.StatusCode != http.StatusNotFound || !strings.Contains(body, `"code":"NotFound"`) {
+		t.Errorf("expected status %d got %d %s", http.StatusNotFound, resp.StatusCode, body)
+	}
+	if resp, _ := do("PUT", "/v1/users/big", strings.Repeat("x", 2048)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d got %d", http.StatusRequestEntityTooLarge, resp.StatusCode)
+	}
+
+	if _, body := do("GET", "/v1/users?limit=1", ""); body != `{"keys":["YW5u"],"next":"Ym9i"}`+"\n" {
+		t.Errorf("expected the first page got %s", body)
+	}
+	if _, body := do("GET", "/v1/users?prefix=Ym8", ""); body != `{"keys":["Ym9i"],"next":""}`+"\n" {
+		t.Errorf("expected bob got %s", body)
+	}
+	if _, body := do("GET", "/v1/", ""); body != `{"namespaces":["users"]}`+"\n" {
+		t.Errorf("expected users got %s", body)
+	}
+
+	if resp, body := do("POST", "/v1/items/_mput", `{"records":[{"key":"YQ","value":"MQ=="},{"key":"Yg","value":"Mg==","ttl":60}]}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d %s", http.StatusNoContent, resp.StatusCode, body)
+	}
+	_, body := do("POST", "/v1/items/_mget", `{"keys":["YQ","Yg","Yw"]}`)
+	var got struct {
+		Values map[string][]byte `json:"values"`
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil || string(got.Values["YQ"]) != "1" || string(got.Values["Yg"]) != "2" || len(got.Values) != 2 {
+		t.Errorf("expected a and b got %s, %v", body, err)
+	}
+	if resp, _ := do("POST", "/v1/items/_mdelete", `{"keys":["YQ"]}`); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp, _ := do("POST", "/v1/items/_mput", `{"records":[{"key":"YQ","value":null}]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp, _ := do("POST", "/v1/items/_mget", `not json`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	if resp, _ := do("PUT", "/v1/%00changes/ann", "1"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp, _ := do("GET", "/v1/%00offsets", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp, _ := do("PUT", "/v1/__changes/ann", "1"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp, _ := do("DELETE", "/v1/users/ann", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp, _ := do("GET", "/v1/users/ann", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestHandlerBinaryKeys(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"), gostore.WithHashedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want [][]byte
+	for _, k := range [][]byte{{0xff, 0x00}, {0xfe}, {0x01, 0xff}} {
+		if err := store.Put("bin", k, []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, k)
+	}
+	// a GET finds what a PUT wrote to a store of hashed keys
+	req := httptest.NewRequest("PUT", "/v1/users/ann", strings.NewReader("1"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/users/ann", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "1" {
+		t.Errorf("expected 1 got %d %s", rec.Code, rec.Body)
+	}
+
+	var got int
+	cursor := ""
+	for {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/bin?limit=1&cursor="+cursor, nil))
+		var page struct {
+			Keys []key `json:"keys"`
+			Next key   `json:"next"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		got += len(page.Keys)
+		if page.Next == nil {
+			break
+		}
+		text, _ := page.Next.MarshalText()
+		cursor = string(text)
+	}
+	if got != len(want) {
+		t.Errorf("expected %d keys got %d", len(want), got)
+	}
+}
+
+func TestHandlerAfter(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range [][]byte{{0x01}, {0x01, 0x00}, {0x01, 0xff}, {0x02}} {
+		if err := store.Put("bin", k, []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enc := func(k []byte) string {
+		text, _ := key(k).MarshalText()
+		return string(text)
+	}
+
+	tests := []struct {
+		query string
+		keys  []string
+	}{
+		{"?after=" + enc([]byte{0x01}), []string{enc([]byte{0x01, 0x00}), enc([]byte{0x01, 0xff}), enc([]byte{0x02})}},
+		{"?prefix=" + enc([]byte{0x01}) + "&after=" + enc([]byte{0x01, 0x00}), []string{enc([]byte{0x01, 0xff})}},
+		{"?prefix=" + enc([]byte{0x01}) + "&after=", []string{enc([]byte{0x01}), enc([]byte{0x01, 0x00}), enc([]byte{0x01, 0xff})}},
+		{"?after=" + enc([]byte{0x02}), []string{}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/bin"+tt.query, nil))
+		var page struct {
+			Keys []string `json:"keys"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(page.Keys, ",") != strings.Join(tt.keys, ",") {
+			t.Errorf("expected keys %v for %s got %v", tt.keys, tt.query, page.Keys)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package httpd
+
+import (
+	"container/list"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// _maxLimitedClients is how many clients the rate limiter tracks before it
+// forgets the least recently seen
+const _maxLimitedClients = 10000
+
+// WithRateLimit limits each client to rate requests a second, with bursts
+// of up to burst requests. Requests beyond it get 429 with a Retry-After
+// header. Clients are told apart by the common name of their TLS client
+// certificate, or else by their IP address.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(o *option) error {
+		if rate <= 0 || burst <= 0 {
+			return errors.New("rate and burst must be positive")
+		}
+		o.rate, o.burst = rate, burst
+		return nil
+	}
+}
+
+// WithMaxInFlight caps the requests served at once. Requests beyond it get
+// 429 right away, so a burst of clients cannot queue up behind the single
+// writer of the store.
+func WithMaxInFlight(n int) Option {
+	return func(o *option) error {
+		if n <= 0 {
+			return errors.New("in-flight limit must be positive")
+		}
+		o.maxInFlight = n
+		return nil
+	}
+}
+
+// rateLimiter is a token bucket per client. It tracks at most maxClients,
+// forgetting the least recently seen, so a client spreading its requests
+// over many addresses costs a bounded amount of memory and time.
+type rateLimiter struct {
+	rate, burst float64
+	maxClients  int
+
+	mu      sync.Mutex
+	clients map[string]*list.Element
+	// recent orders the buckets from the most recently seen client
+	recent list.List
+}
+
+type tokenBucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), maxClients: _maxLimitedClients,
+		clients: make(map[string]*list.Element)}
+}
+
+// allow takes a token of client, returning how long to wait for one if
+// there is none
+func (l *rateLimiter) allow(client string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b *tokenBucket
+	if e := l.clients[client]; e != nil {
+		l.recent.MoveToFront(e)
+		b = e.Value.(*tokenBucket)
+	} else {
+		if l.recent.Len() >= l.maxClients {
+			oldest := l.recent.Back()
+			l.recent.Remove(oldest)
+			delete(l.clients, oldest.Value.(*tokenBucket).client)
+		}
+		b = &tokenBucket{client: client, tokens: l.burst, last: now}
+		l.clients[client] = l.recent.PushFront(b)
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// clientID returns what tells the client of r apart for rate limiting
+func clientID(r *http.Request) string {
+	if name, ok := clientName(r); ok {
+		return "cn:" + name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// limit admits r under the rate and in-flight limits, returning the
+// function to call once it is served, or replies with 429 and returns nil
+func (h *Handler) limit(w http.ResponseWriter, r *http.Request) func() {
+	if h.limiter != nil {
+		if wait, ok := h.limiter.allow(clientID(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return nil
+		}
+	}
+	if h.inFlight == nil {
+		return func() {}
+	}
+	select {
+	case h.inFlight <- struct{}{}:
+		return func() { <-h.inFlight }
+	default:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many requests in flight", http.StatusTooManyRequests)
+		return nil
+	}
+}
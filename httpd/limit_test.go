@@ -0,0 +1,88 @@
+package httpd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/millken/gostore"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 2)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, ok := l.allow("a", now); !ok {
+			t.Errorf("expected request %d allowed", i)
+		}
+	}
+	if wait, ok := l.allow("a", now); ok || wait != 500*time.Millisecond {
+		t.Errorf("expected a wait of 500ms got %s, %v", wait, ok)
+	}
+	if _, ok := l.allow("b", now); !ok {
+		t.Error("expected another client allowed")
+	}
+	if _, ok := l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Error("expected a token after 500ms")
+	}
+}
+
+func TestHandlerLimits(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h, err := New(store, WithRateLimit(1, 2), WithMaxInFlight(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	for i := 0; i < 2; i++ {
+		if rec := get("10.0.0.1:1000"); rec.Code != http.StatusOK {
+			t.Errorf("expected status %d got %d", http.StatusOK, rec.Code)
+		}
+	}
+	if rec := get("10.0.0.1:1001"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected status %d with Retry-After got %d %v", http.StatusTooManyRequests, rec.Code, rec.Header())
+	}
+	if rec := get("10.0.0.2:1000"); rec.Code != http.StatusOK {
+		t.Errorf("expected status %d got %d", http.StatusOK, rec.Code)
+	}
+
+	// the only in-flight slot is taken
+	h.inFlight <- struct{}{}
+	if rec := get("10.0.0.3:1000"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	<-h.inFlight
+	if rec := get("10.0.0.3:1000"); rec.Code != http.StatusOK {
+		t.Errorf("expected status %d got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	l.maxClients = 2
+	now := time.Now()
+	for _, client := range []string{"a", "b", "a", "c"} {
+		l.allow(client, now)
+	}
+	if len(l.clients) != 2 || l.recent.Len() != 2 {
+		t.Errorf("expected 2 clients got %d, %d", len(l.clients), l.recent.Len())
+	}
+	if _, ok := l.clients["b"]; ok {
+		t.Error("expected the least recently seen client forgotten")
+	}
+	if _, ok := l.allow("a", now); ok {
+		t.Error("expected a still limited")
+	}
+}
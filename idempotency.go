@@ -0,0 +1,58 @@
+package gostore
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_idempotencyBucket     = _internalPrefix + "idempotency"
+	_defaultIdempotencyTTL = 24 * time.Hour
+)
+
+// WithIdempotencyTTL sets how long PutIdempotent remembers a token
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(o *option) error {
+		o.idempotencyTTL = ttl
+		return nil
+	}
+}
+
+// PutIdempotent inserts a <key, value> record unless a write with the same
+// token was already applied. Tokens are remembered for the idempotency TTL,
+// making retried writes from at-least-once pipelines safe.
+func (s *Store) PutIdempotent(namespace string, key, value []byte, token string) error {
+	ttl := s.opt.Load().idempotencyTTL
+	if ttl <= 0 {
+		ttl = _defaultIdempotencyTTL
+	}
+	err := s.update(func(tx *bolt.Tx) error {
+		tokens, err := tx.CreateBucketIfNotExists([]byte(_idempotencyBucket))
+		if err != nil {
+			return err
+		}
+		if seen := tokens.Get([]byte(token)); seen != nil {
+			if expire, ok := expireOf(seen); ok && time.Now().Before(expire) {
+				return nil
+			}
+		}
+		buf, err := newValueT(value, 0).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := s.put(tx, []byte(namespace), s.diskKey(key), buf); err != nil {
+			return err
+		}
+		mark, err := (&valueT{Expire: time.Now().Add(ttl)}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return tokens.Put([]byte(token), mark)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+	return err
+}
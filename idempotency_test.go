@@ -0,0 +1,45 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPutIdempotent(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.PutIdempotent("test", []byte("key"), []byte("value1"), "token1"); err != nil {
+		t.Error(err)
+	}
+	// a retry of the same write with a different payload is ignored
+	if err := s.PutIdempotent("test", []byte("key"), []byte("value2"), "token1"); err != nil {
+		t.Error(err)
+	}
+	value, err := s.Get([]byte("test"), []byte("key"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(value) != "value1" {
+		t.Errorf("expected value %s, got %s", "value1", value)
+	}
+
+	if err := s.PutIdempotent("test", []byte("key"), []byte("value3"), "token2"); err != nil {
+		t.Error(err)
+	}
+	value, err = s.Get([]byte("test"), []byte("key"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(value) != "value3" {
+		t.Errorf("expected value %s, got %s", "value3", value)
+	}
+}
@@ -0,0 +1,58 @@
+package gostore
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrImmutable is returned when a write would overwrite or delete a key
+	// of an immutable namespace.
+	ErrImmutable = errors.New("namespace is immutable")
+
+	// ErrKeyExists is returned when a write would overwrite a key of a
+	// namespace set up by WithNoOverwrite.
+	ErrKeyExists = errors.New("key exists")
+)
+
+// WithImmutableNamespace makes a namespace write-once: keys can be added,
+// but never overwritten or deleted. Expired keys are not collected either.
+func WithImmutableNamespace(namespace string) Option {
+	return func(o *option) error {
+		if o.immutable == nil {
+			o.immutable = make(map[string]struct{})
+		}
+		o.immutable[namespace] = struct{}{}
+		return nil
+	}
+}
+
+func (s *Store) isImmutable(namespace []byte) bool {
+	_, ok := s.opt.Load().immutable[string(namespace)]
+	return ok
+}
+
+// WithNoOverwrite makes writes to a namespace fail with ErrKeyExists when
+// the key is present and not expired, CAS, Incr and Touch included. Unlike
+// WithImmutableNamespace keys can still be deleted, and then written again.
+func WithNoOverwrite(namespace string) Option {
+	return func(o *option) error {
+		if o.noOverwrite == nil {
+			o.noOverwrite = make(map[string]struct{})
+		}
+		o.noOverwrite[namespace] = struct{}{}
+		return nil
+	}
+}
+
+// checkOverwrite returns ErrKeyExists if old is a live record of a
+// namespace set up by WithNoOverwrite
+func (s *Store) checkOverwrite(namespace, old []byte) error {
+	if _, ok := s.opt.Load().noOverwrite[string(namespace)]; !ok || old == nil {
+		return nil
+	}
+	if expire, ok := expireOf(old); ok && !expire.IsZero() && time.Now().After(expire) {
+		return nil
+	}
+	return ErrKeyExists
+}
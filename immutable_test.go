@@ -0,0 +1,90 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestImmutableNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithImmutableNamespace("audit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("audit", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("audit", []byte("key"), []byte("value2")); !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %s", ErrImmutable, err)
+	}
+	if err := s.Delete("audit", []byte("key")); !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %s", ErrImmutable, err)
+	}
+	if err := s.DeleteNamespace("audit"); !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %s", ErrImmutable, err)
+	}
+	if err := s.Delete("audit", []byte("missing")); err != nil {
+		t.Error(err)
+	}
+	value, err := s.Get([]byte("audit"), []byte("key"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(value) != "value" {
+		t.Errorf("expected value %s, got %s", "value", value)
+	}
+	// other namespaces still accept overwrites
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("test", []byte("key"), []byte("value2")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNoOverwrite(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithNoOverwrite("registry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("registry"), []byte("service")
+	if err := s.Put(string(ns), key, []byte("v1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), key, []byte("v2")); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("expected error %s, got %v", ErrKeyExists, err)
+	}
+	if Code(ErrKeyExists) != CodeConflict {
+		t.Errorf("expected code %s got %s", CodeConflict, Code(ErrKeyExists))
+	}
+	if v, err := s.Get(ns, key); err != nil || string(v) != "v1" {
+		t.Errorf("expected v1 got %s, %v", v, err)
+	}
+	if err := s.Delete(string(ns), key); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), key, []byte("v2")); err != nil {
+		t.Error(err)
+	}
+	// other namespaces are not affected
+	if err := s.Put("other", key, []byte("v1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("other", key, []byte("v2")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,83 @@
+package gostore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const _importBatchSize = 1000
+
+// ImportDir loads the files of a directory into a namespace, using the path
+// relative to dir as key and the contents as value. It returns the number of
+// files imported.
+func (s *Store) ImportDir(dir string, ns string) (int, error) {
+	return s.ImportDirWithTTL(dir, ns, 0)
+}
+
+// ImportDirWithTTL is like ImportDir, but each record expires maxAge after
+// the modification time of its file. Files older than maxAge are skipped.
+func (s *Store) ImportDirWithTTL(dir string, ns string, maxAge time.Duration) (int, error) {
+	var (
+		batch []KV
+		size  int
+		total int
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.putBatch([]byte(ns), batch)
+		if err == nil {
+			total += len(batch)
+			batch, size = batch[:0], 0
+		}
+		return err
+	}
+
+	now := time.Now()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		value := valueT{}
+		if maxAge > 0 {
+			value.Expire = info.ModTime().Add(maxAge)
+			if now.After(value.Expire) {
+				return nil
+			}
+		}
+		if value.Value, err = os.ReadFile(path); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		buf, err := value.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		key := s.diskKey([]byte(filepath.ToSlash(rel)))
+		if len(batch) > 0 && size+len(key)+len(buf) > s.maxTxSize() {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, KV{Key: key, Value: buf})
+		size += len(key) + len(buf)
+		if len(batch) >= _importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return total, err
+	}
+	return total, flush()
+}
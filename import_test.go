@@ -0,0 +1,73 @@
+package gostore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImportDir(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "import_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.txt":     "aa",
+		"sub/b.txt": "bb",
+		"old.txt":   "old",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	n, err := s.ImportDir(dir, "all")
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 3 {
+		t.Errorf("expected %d files imported, got %d", 3, n)
+	}
+	for name, content := range files {
+		value, err := s.Get([]byte("all"), []byte(name))
+		if err != nil {
+			t.Error(err)
+		}
+		if string(value) != content {
+			t.Errorf("expected value %s, got %s", content, value)
+		}
+	}
+
+	n, err = s.ImportDirWithTTL(dir, "fresh", time.Hour)
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 2 {
+		t.Errorf("expected %d files imported, got %d", 2, n)
+	}
+	if _, err := s.Get([]byte("fresh"), []byte("old.txt")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %s", ErrKeyNotFound, err)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package gostore
+
+import (
+	"errors"
+	"iter"
+)
+
+// errStopIteration ends iterateChunks when the loop body breaks
+var errStopIteration = errors.New("stop iteration")
+
+// All returns an iterator over the live records of a namespace in key
+// order, read in chunks as SnapshotIterate does, so the loop body may
+// write to the store. Iteration ends early if a read fails.
+func (s *Store) All(namespace []byte) iter.Seq2[[]byte, []byte] {
+	return s.Prefix(namespace, nil)
+}
+
+// Prefix is like All, but only yields the records whose key starts with
+// prefix. With WithHashedKeys a non-empty prefix yields nothing.
+func (s *Store) Prefix(namespace, prefix []byte) iter.Seq2[[]byte, []byte] {
+	return func(yield func(k, v []byte) bool) {
+		_ = s.iterateChunks(namespace, prefix, 0, func(k, v []byte) error {
+			if !yield(k, v) {
+				return errStopIteration
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,59 @@
+//go:build go1.23
+
+package gostore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("ns")
+	for i := 0; i < 5; i++ {
+		if err := s.Put(string(ns), []byte(fmt.Sprintf("a%d", i)), []byte("v")); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.Put(string(ns), []byte("b"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+
+	n := 0
+	for range s.All(ns) {
+		n++
+	}
+	if n != 6 {
+		t.Errorf("expected 6 records got %d", n)
+	}
+	var keys []string
+	for k := range s.Prefix(ns, []byte("a")) {
+		keys = append(keys, string(k))
+		if len(keys) == 2 {
+			break
+		}
+	}
+	if fmt.Sprint(keys) != "[a0 a1]" {
+		t.Errorf("expected [a0 a1] got %v", keys)
+	}
+	for k, v := range s.Prefix(ns, []byte("b")) {
+		// the body may write to the store
+		if err := s.Put(string(ns), k, append(v, '!')); err != nil {
+			t.Error(err)
+		}
+	}
+	if v, err := s.Get(ns, []byte("b")); err != nil || string(v) != "v!" {
+		t.Errorf("expected v! got %s, %v", v, err)
+	}
+}
@@ -0,0 +1,126 @@
+package gostore
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeyGenerator returns a new unique key.
+type KeyGenerator func() ([]byte, error)
+
+// PutNew inserts value under a key made by gen and returns the key. It
+// fails with ErrKeyExists rather than overwrite a record if gen repeats a
+// key.
+func (s *Store) PutNew(ns string, value []byte, gen KeyGenerator) (key []byte, err error) {
+	if key, err = gen(); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	stored, err := s.PutNX([]byte(ns), key, value, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, fmt.Errorf("failed to put key %s: %w", key, ErrKeyExists)
+	}
+	return key, nil
+}
+
+// UUIDv7 generates RFC 9562 version 7 UUIDs in their 36 character text
+// form, which sort by creation time to the millisecond.
+func UUIDv7() ([]byte, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+	putMillis(u[:6], time.Now())
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+	key := make([]byte, 36)
+	hex.Encode(key[0:8], u[0:4])
+	hex.Encode(key[9:13], u[4:6])
+	hex.Encode(key[14:18], u[6:8])
+	hex.Encode(key[19:23], u[8:10])
+	hex.Encode(key[24:], u[10:])
+	key[8], key[13], key[18], key[23] = '-', '-', '-', '-'
+	return key, nil
+}
+
+const _crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates ULIDs in their 26 character text form, which sort by
+// creation time to the millisecond.
+func ULID() ([]byte, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return nil, err
+	}
+	putMillis(u[:6], time.Now())
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	key := make([]byte, 26)
+	for i := len(key) - 1; i >= 0; i-- {
+		key[i] = _crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return key, nil
+}
+
+// putMillis writes the 48 bit Unix time of t in milliseconds to b
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+const (
+	_snowflakeNodeBits = 10
+	_snowflakeSeqBits  = 12
+)
+
+// _snowflakeEpoch is 2024-01-01 in Unix milliseconds
+const _snowflakeEpoch = 1704067200000
+
+// NewSnowflake returns a generator of snowflake IDs for node, which must
+// be below 1024 and unique among the processes writing to the same
+// namespace. Keys are 8 bytes, big-endian: 41 bits of milliseconds since
+// 2024, the node and a 12 bit sequence, so they sort by creation time and
+// never repeat within the process.
+func NewSnowflake(node uint16) (KeyGenerator, error) {
+	if node >= 1<<_snowflakeNodeBits {
+		return nil, fmt.Errorf("%w: snowflake node %d", ErrBadValue, node)
+	}
+	var (
+		mu   sync.Mutex
+		last int64
+		seq  uint64
+	)
+	return func() ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now().UnixMilli() - _snowflakeEpoch
+		if now < last {
+			// the clock went back, keep counting from the last time
+			now = last
+		}
+		if now == last {
+			seq = (seq + 1) & (1<<_snowflakeSeqBits - 1)
+			if seq == 0 {
+				for now <= last {
+					time.Sleep(time.Millisecond / 10)
+					now = time.Now().UnixMilli() - _snowflakeEpoch
+				}
+			}
+		} else {
+			seq = 0
+		}
+		last = now
+		id := uint64(now)<<(_snowflakeNodeBits+_snowflakeSeqBits) | uint64(node)<<_snowflakeSeqBits | seq
+		return binary.BigEndian.AppendUint64(nil, id), nil
+	}, nil
+}
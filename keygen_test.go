@@ -0,0 +1,88 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPutNew(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	snowflake, err := NewSnowflake(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, gen := range map[string]KeyGenerator{"uuidv7": UUIDv7, "ulid": ULID, "snowflake": snowflake} {
+		var last []byte
+		for i := 0; i < 3; i++ {
+			key, err := s.PutNew(name, []byte("value"), gen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Compare(key, last) <= 0 {
+				t.Errorf("%s: expected %x after %x", name, key, last)
+			}
+			last = key
+			// keys of later milliseconds sort after
+			time.Sleep(2 * time.Millisecond)
+		}
+		if n, err := s.Count([]byte(name)); err != nil || n != 3 {
+			t.Errorf("%s: expected 3 records got %d, %v", name, n, err)
+		}
+	}
+
+	same := func() ([]byte, error) { return []byte("k"), nil }
+	if _, err := s.PutNew("ns", []byte("value"), same); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.PutNew("ns", []byte("value"), same); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("expected error %s, got %v", ErrKeyExists, err)
+	}
+	if _, err := NewSnowflake(1024); !errors.Is(err, ErrBadValue) {
+		t.Errorf("expected error %s, got %v", ErrBadValue, err)
+	}
+}
+
+func TestKeyGeneratorFormats(t *testing.T) {
+	key, err := UUIDv7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).Match(key) {
+		t.Errorf("expected a version 7 UUID got %s", key)
+	}
+	if key, err = ULID(); err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).Match(key) {
+		t.Errorf("expected a ULID got %s", key)
+	}
+	gen, err := NewSnowflake(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		key, err := gen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[string(key)] {
+			t.Fatalf("repeated snowflake %x", key)
+		}
+		seen[string(key)] = true
+	}
+}
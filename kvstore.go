@@ -0,0 +1,22 @@
+package gostore
+
+// KVStore is the basic interface of a key-value store. Store implements it;
+// other implementations can be used as shadow or dual-write targets during
+// a migration.
+type KVStore interface {
+	Put(namespace string, key, value []byte) error
+	Get(namespace, key []byte) ([]byte, error)
+	Delete(namespace string, key []byte) error
+}
+
+// KeyLister is implemented by stores that can list their keys, such as
+// Store. VerifyDualWrite uses it to find keys only the target holds.
+type KeyLister interface {
+	Namespaces() ([]string, error)
+	Keys(namespace []byte, cursor []byte, limit int) (keys [][]byte, nextCursor []byte, err error)
+}
+
+var (
+	_ KVStore   = (*Store)(nil)
+	_ KeyLister = (*Store)(nil)
+)
@@ -0,0 +1,90 @@
+package gostore
+
+import (
+	"context"
+	"encoding"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// LoadMany loads several keys at once: cache misses are read in a single
+// transaction and all values are unmarshaled in parallel. The returned map
+// holds the error of every key that failed to load; the error is only set
+// when the context is done or the transaction failed.
+func (s *Store) LoadMany(ctx context.Context, objs map[string]encoding.BinaryUnmarshaler) (map[string]error, error) {
+	var (
+		errs   = make(map[string]error)
+		values = make(map[string][]byte, len(objs))
+		misses []string
+	)
+	for key, obj := range objs {
+		if obj == nil {
+			errs[key] = ErrBadValue
+			continue
+		}
+		if v, ok := s.cacheGet(string(s.diskKey([]byte(key)))); ok {
+			values[key] = v
+			continue
+		}
+		misses = append(misses, key)
+	}
+
+	if len(misses) > 0 {
+		if err := s.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(_defaultBucket))
+			now := time.Now()
+			for _, key := range misses {
+				dk := s.diskKey([]byte(key))
+				v, ok := s.overlay.get([]byte(_defaultBucket), dk)
+				if !ok && bucket != nil {
+					v = bucket.Get(dk)
+				}
+				if v == nil {
+					errs[key] = ErrKeyNotFound
+					continue
+				}
+				var value valueT
+				if err := value.UnmarshalBinary(v); err != nil {
+					errs[key] = ErrCorrupted
+					continue
+				}
+				if !value.Expire.IsZero() && now.After(value.Expire) {
+					errs[key] = ErrKeyExpired
+					s.notifyExpired([]byte(_defaultBucket), dk, value.Expire)
+					continue
+				}
+				values[key] = value.Value
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var mu sync.Mutex
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.GOMAXPROCS(0))
+	for key, v := range values {
+		key, v := key, v
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := objs[key].UnmarshalBinary(v); err != nil {
+				mu.Lock()
+				errs[key] = err
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
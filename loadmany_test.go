@@ -0,0 +1,46 @@
+package gostore
+
+import (
+	"context"
+	"encoding"
+	"os"
+	"testing"
+)
+
+func TestLoadMany(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := s.Update(k, &T1{Name: k}); err != nil {
+			t.Error(err)
+		}
+	}
+	var a, b, c, missing T1
+	errs, err := s.LoadMany(context.Background(), map[string]encoding.BinaryUnmarshaler{
+		"a": &a, "b": &b, "c": &c, "missing": &missing,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs["missing"] != ErrKeyNotFound {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if a.Name != "a" || b.Name != "b" || c.Name != "c" {
+		t.Errorf("unexpected values %v %v %v", a, b, c)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.LoadMany(ctx, map[string]encoding.BinaryUnmarshaler{"a": &a}); err != context.Canceled {
+		t.Errorf("expected error %s, got %v", context.Canceled, err)
+	}
+}
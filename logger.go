@@ -0,0 +1,32 @@
+package gostore
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger logs retried and failed transactions, garbage collection
+// passes and compactions to l. By default nothing is logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *option) error {
+		o.logger = l
+		return nil
+	}
+}
+
+// logger returns the configured logger, or one discarding everything
+func (s *Store) logger() *slog.Logger {
+	if l := s.opt.Load().logger; l != nil {
+		return l
+	}
+	return _discardLogger
+}
+
+var _discardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
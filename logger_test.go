@@ -0,0 +1,78 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogger(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+
+	var out syncBuffer
+	errInjected := errors.New("injected")
+	fail := false
+	s, err := Open(path,
+		WithLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithFailpoints(Failpoints{BeforeCommit: func() error {
+			if fail {
+				return errInjected
+			}
+			return nil
+		}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fail = true
+	if err := s.Put("ns", []byte("key"), []byte("value")); !errors.Is(err, errInjected) {
+		t.Errorf("expected error %s, got %v", errInjected, err)
+	}
+	fail = false
+	if _, err := s.GC(); err != nil {
+		t.Error(err)
+	}
+	if err := s.Compact(); err != nil {
+		t.Error(err)
+	}
+
+	logged := out.String()
+	for _, msg := range []string{
+		"gostore: retrying transaction", "attempt=2",
+		"gostore: transaction failed", "attempts=3",
+		"gostore: gc pass",
+		"gostore: compacted",
+	} {
+		if !strings.Contains(logged, msg) {
+			t.Errorf("expected %q logged got:\n%s", msg, logged)
+		}
+	}
+}
@@ -2,84 +2,365 @@ package gostore
 
 import (
 	"container/list"
+	"hash/maphash"
+	"sync"
 	"time"
 )
 
+// Priority controls the order in which cache entries are evicted. Entries
+// of a lower priority are always evicted before entries of a higher one.
+type Priority uint8
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	numPriorities
+)
+
 type lru struct {
-	evictList *list.List
-	items     map[string]*list.Element
-	size      int
+	mu         sync.Mutex
+	evictLists [numPriorities]*list.List
+	items      map[string]*list.Element
+	size       int
+	// maxBytes limits the bytes of keys and values held, zero means no
+	// limit
+	maxBytes int64
+	bytes    int64
+	stats    ShardStats
+	// onEvict is called with the keys evicted to make room, after the
+	// lock is released
+	onEvict func(key string)
 }
 
 // entry is used to hold a value in the evictList
 type entry struct {
-	key    string
-	expire time.Time
-	value  []byte
+	key      string
+	expire   time.Time
+	value    []byte
+	priority Priority
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.value))
 }
 
 func newLRU(size int) *lru {
-	return &lru{
-		evictList: list.New(),
-		items:     make(map[string]*list.Element),
-		size:      size,
+	l := &lru{
+		items: make(map[string]*list.Element),
+		size:  size,
+	}
+	for i := range l.evictLists {
+		l.evictLists[i] = list.New()
 	}
+	return l
 }
 
-// Add adds a value to the cache.
+// Add adds a value to the cache with normal priority.
 func (l *lru) Add(key string, expire time.Time, value []byte) {
+	l.AddWithPriority(key, expire, value, PriorityNormal)
+}
+
+// AddWithPriority adds a value to the cache with the given priority.
+func (l *lru) AddWithPriority(key string, expire time.Time, value []byte, p Priority) {
+	if p >= numPriorities {
+		p = PriorityHigh
+	}
+	l.mu.Lock()
+	ent := &entry{key, expire, value, p}
 	// Check for existing item
-	if ent, ok := l.items[key]; ok {
-		l.evictList.MoveToFront(ent)
-		ent.Value.(*entry).value = value
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+	if l.maxBytes > 0 && ent.size() > l.maxBytes {
+		// it would push everything else out
+		l.mu.Unlock()
 		return
 	}
 
 	// Add new item
-	ent := &entry{key, expire, value}
-	entry := l.evictList.PushFront(ent)
-	l.items[key] = entry
-
-	evict := l.evictList.Len() > l.size
-	// Verify size not exceeded
-	if evict {
-		l.removeOldest()
-	}
+	l.items[key] = l.evictLists[p].PushFront(ent)
+	l.bytes += ent.size()
+	l.unlockEvicted(l.trim())
 }
 
 // Get looks up a key's value from the cache.
 func (l *lru) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if ent, ok := l.items[key]; ok {
-		l.evictList.MoveToFront(ent)
-		if ent.Value.(*entry) == nil {
-			return nil, false
-		}
-		if ent.Value.(*entry).expire.IsZero() || ent.Value.(*entry).expire.After(time.Now()) {
-			return ent.Value.(*entry).value, true
+		e := ent.Value.(*entry)
+		l.evictLists[e.priority].MoveToFront(ent)
+		if e.expire.IsZero() || e.expire.After(time.Now()) {
+			l.stats.Hits++
+			return e.value, true
 		}
 		l.removeElement(ent)
+		l.stats.Expirations++
 	}
+	l.stats.Misses++
 	return nil, false
 }
 
 // Delete deletes a key from the cache.
 func (l *lru) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if ent, ok := l.items[key]; ok {
 		l.removeElement(ent)
 	}
 }
 
-// removeOldest removes the oldest item from the cache.
-func (l *lru) removeOldest() {
-	ent := l.evictList.Back()
-	if ent != nil {
-		l.removeElement(ent)
+// DeleteFunc deletes the keys for which match returns true.
+func (l *lru) DeleteFunc(match func(key string) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, ent := range l.items {
+		if match(key) {
+			l.removeElement(ent)
+		}
+	}
+}
+
+// Resize changes the maximum number of items, evicting as needed.
+func (l *lru) Resize(size int) {
+	l.mu.Lock()
+	l.size = size
+	l.unlockEvicted(l.trim())
+}
+
+// SetMaxBytes changes the maximum number of bytes, evicting as needed.
+func (l *lru) SetMaxBytes(n int64) {
+	l.mu.Lock()
+	l.maxBytes = n
+	l.unlockEvicted(l.trim())
+}
+
+// trim evicts items until the cache is within its limits. It returns the
+// evicted keys if there is an onEvict callback.
+func (l *lru) trim() []string {
+	var evicted []string
+	for len(l.items) > l.size || (l.maxBytes > 0 && l.bytes > l.maxBytes) {
+		key := l.removeOldest()
+		if l.onEvict != nil {
+			evicted = append(evicted, key)
+		}
+	}
+	return evicted
+}
+
+// unlockEvicted releases the lock and then reports the evicted keys, so
+// onEvict may use the cache
+func (l *lru) unlockEvicted(evicted []string) {
+	l.mu.Unlock()
+	for _, key := range evicted {
+		l.onEvict(key)
 	}
 }
 
+// Cap returns the maximum number of items.
+func (l *lru) Cap() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// MaxBytes returns the maximum number of bytes, zero for no limit.
+func (l *lru) MaxBytes() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.maxBytes
+}
+
+// Len returns the number of items in the cache.
+func (l *lru) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// Stats returns the statistics of the cache.
+func (l *lru) Stats() ShardStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.Len, stats.Cap, stats.Bytes = len(l.items), l.size, l.bytes
+	return stats
+}
+
+// removeOldest removes the oldest item of the lowest priority from the cache
+// and returns its key.
+func (l *lru) removeOldest() string {
+	for _, evictList := range l.evictLists {
+		if ent := evictList.Back(); ent != nil {
+			l.removeElement(ent)
+			l.stats.Evictions++
+			return ent.Value.(*entry).key
+		}
+	}
+	return ""
+}
+
 // removeElement is used to remove a given list element from the cache
 func (l *lru) removeElement(e *list.Element) {
-	l.evictList.Remove(e)
 	kv := e.Value.(*entry)
+	l.evictLists[kv.priority].Remove(e)
 	delete(l.items, kv.key)
+	l.bytes -= kv.size()
+}
+
+// ShardStats are the statistics of one cache shard.
+type ShardStats struct {
+	Len       int
+	Cap       int
+	Bytes     int64
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Expirations counts the expired items found by lookups, which are
+	// counted as misses too.
+	Expirations uint64
+}
+
+// CacheStats are the statistics of the LRU cache.
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+	Bytes       int64
+}
+
+// HitRate returns the fraction of lookups that hit, zero without lookups
+func (c CacheStats) HitRate() float64 {
+	if c.Hits+c.Misses == 0 {
+		return 0
+	}
+	return float64(c.Hits) / float64(c.Hits+c.Misses)
+}
+
+// shardedLRU spreads keys over several LRU caches by hash, so concurrent
+// readers rarely wait on the same lock. Each shard evicts on its own.
+type shardedLRU struct {
+	seed   maphash.Seed
+	shards []*lru
+}
+
+// newShardedLRU returns a cache of size items split over n shards. There
+// are never more shards than items.
+func newShardedLRU(size, n int) *shardedLRU {
+	n = max(1, min(n, size))
+	l := &shardedLRU{seed: maphash.MakeSeed(), shards: make([]*lru, n)}
+	for i := range l.shards {
+		l.shards[i] = newLRU(shardSize(size, n, i))
+	}
+	return l
+}
+
+// shardSize returns the share of size of shard i out of n
+func shardSize(size, n, i int) int {
+	if i < size%n {
+		return size/n + 1
+	}
+	return size / n
+}
+
+func (l *shardedLRU) shard(key string) *lru {
+	if len(l.shards) == 1 {
+		return l.shards[0]
+	}
+	return l.shards[maphash.String(l.seed, key)%uint64(len(l.shards))]
+}
+
+// OnEvict sets a function called with the keys evicted to make room.
+func (l *shardedLRU) OnEvict(fn func(key string)) {
+	for _, shard := range l.shards {
+		shard.mu.Lock()
+		shard.onEvict = fn
+		shard.mu.Unlock()
+	}
+}
+
+// Add adds a value to the cache with normal priority.
+func (l *shardedLRU) Add(key string, expire time.Time, value []byte) {
+	l.shard(key).Add(key, expire, value)
+}
+
+// AddWithPriority adds a value to the cache with the given priority.
+func (l *shardedLRU) AddWithPriority(key string, expire time.Time, value []byte, p Priority) {
+	l.shard(key).AddWithPriority(key, expire, value, p)
+}
+
+// Get looks up a key's value from the cache.
+func (l *shardedLRU) Get(key string) ([]byte, bool) {
+	return l.shard(key).Get(key)
+}
+
+// Delete deletes a key from the cache.
+func (l *shardedLRU) Delete(key string) {
+	l.shard(key).Delete(key)
+}
+
+// DeleteFunc deletes the keys for which match returns true.
+func (l *shardedLRU) DeleteFunc(match func(key string) bool) {
+	for _, shard := range l.shards {
+		shard.DeleteFunc(match)
+	}
+}
+
+// Resize changes the maximum number of items, splitting it over the shards.
+func (l *shardedLRU) Resize(size int) {
+	for i, shard := range l.shards {
+		shard.Resize(shardSize(size, len(l.shards), i))
+	}
+}
+
+// SetMaxBytes changes the maximum number of bytes, splitting it over the
+// shards. Zero means no limit.
+func (l *shardedLRU) SetMaxBytes(n int64) {
+	for i, shard := range l.shards {
+		size := int64(shardSize(int(n), len(l.shards), i))
+		if n > 0 && size == 0 {
+			// zero would lift the limit of the shard
+			size = 1
+		}
+		shard.SetMaxBytes(size)
+	}
+}
+
+// Cap returns the maximum number of items.
+func (l *shardedLRU) Cap() int {
+	n := 0
+	for _, shard := range l.shards {
+		n += shard.Cap()
+	}
+	return n
+}
+
+// MaxBytes returns the maximum number of bytes, zero for no limit.
+func (l *shardedLRU) MaxBytes() int64 {
+	var n int64
+	for _, shard := range l.shards {
+		n += shard.MaxBytes()
+	}
+	return n
+}
+
+// Len returns the number of items in the cache.
+func (l *shardedLRU) Len() int {
+	n := 0
+	for _, shard := range l.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Stats returns the statistics of every shard.
+func (l *shardedLRU) Stats() []ShardStats {
+	stats := make([]ShardStats, len(l.shards))
+	for i, shard := range l.shards {
+		stats[i] = shard.Stats()
+	}
+	return stats
 }
@@ -2,6 +2,7 @@ package gostore
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -33,3 +34,106 @@ func TestLRU(t *testing.T) {
 		t.Error("expected key2 to be evicted")
 	}
 }
+
+func TestLRUPriority(t *testing.T) {
+	lru := newLRU(2)
+	lru.AddWithPriority("high", time.Time{}, []byte("value1"), PriorityHigh)
+	lru.AddWithPriority("low", time.Time{}, []byte("value2"), PriorityLow)
+	lru.Add("normal", time.Time{}, []byte("value3"))
+	if _, ok := lru.Get("low"); ok {
+		t.Error("expected low to be evicted")
+	}
+	lru.Add("normal2", time.Time{}, []byte("value4"))
+	if _, ok := lru.Get("normal"); ok {
+		t.Error("expected normal to be evicted")
+	}
+	if _, ok := lru.Get("high"); !ok {
+		t.Error("expected high to be in cache")
+	}
+
+	// re-adding with a lower priority demotes the entry
+	lru.AddWithPriority("high", time.Time{}, []byte("value1"), PriorityLow)
+	lru.Add("normal3", time.Time{}, []byte("value5"))
+	if _, ok := lru.Get("high"); ok {
+		t.Error("expected high to be evicted")
+	}
+}
+
+func TestLRUResize(t *testing.T) {
+	lru := newLRU(3)
+	lru.Add("key1", time.Time{}, []byte("value1"))
+	lru.Add("key2", time.Time{}, []byte("value2"))
+	lru.Add("key3", time.Time{}, []byte("value3"))
+	lru.Resize(1)
+	if lru.Len() != 1 {
+		t.Errorf("expected %d items, got %d", 1, lru.Len())
+	}
+	if _, ok := lru.Get("key3"); !ok {
+		t.Error("expected key3 to be in cache")
+	}
+}
+
+func TestShardedLRU(t *testing.T) {
+	lru := newShardedLRU(10, 4)
+	if len(lru.shards) != 4 {
+		t.Errorf("expected %d shards, got %d", 4, len(lru.shards))
+	}
+	if lru.Cap() != 10 {
+		t.Errorf("expected cap %d, got %d", 10, lru.Cap())
+	}
+	for i := 0; i < 100; i++ {
+		lru.Add(fmt.Sprint(i), time.Time{}, []byte("value"))
+	}
+	if lru.Len() > 10 {
+		t.Errorf("expected at most %d items, got %d", 10, lru.Len())
+	}
+	lru.Get("99")
+	lru.Get("missing")
+
+	var stats ShardStats
+	for _, s := range lru.Stats() {
+		stats.Len += s.Len
+		stats.Hits += s.Hits
+		stats.Misses += s.Misses
+		stats.Evictions += s.Evictions
+	}
+	if stats.Len != lru.Len() || stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != uint64(100-stats.Len) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	lru.Resize(2)
+	if lru.Cap() != 2 || lru.Len() > 2 {
+		t.Errorf("expected cap 2, got %d with %d items", lru.Cap(), lru.Len())
+	}
+	if got := len(newShardedLRU(2, 8).shards); got != 2 {
+		t.Errorf("expected %d shards, got %d", 2, got)
+	}
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	lru := newLRU(100)
+	lru.SetMaxBytes(20)
+	lru.Add("a", time.Time{}, []byte("123456789"))
+	lru.Add("b", time.Time{}, []byte("123456789"))
+	if lru.Len() != 2 {
+		t.Errorf("expected 2 items, got %d", lru.Len())
+	}
+	lru.Add("c", time.Time{}, []byte("123"))
+	if _, ok := lru.Get("a"); ok {
+		t.Error("expected a to be evicted")
+	}
+	if stats := lru.Stats(); stats.Bytes != 14 {
+		t.Errorf("expected 14 bytes, got %d", stats.Bytes)
+	}
+	lru.Add("huge", time.Time{}, make([]byte, 100))
+	if _, ok := lru.Get("huge"); ok {
+		t.Error("expected huge not to be cached")
+	}
+	if lru.Len() != 2 {
+		t.Errorf("expected 2 items, got %d", lru.Len())
+	}
+	lru.Add("b", time.Time{}, []byte("1"))
+	if stats := lru.Stats(); stats.Bytes != 6 {
+		t.Errorf("expected 6 bytes, got %d", stats.Bytes)
+	}
+}
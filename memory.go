@@ -0,0 +1,167 @@
+package gostore
+
+import (
+	"errors"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+const (
+	_memoryCheckInterval = time.Second
+	_memoryHighWater     = 0.9 // shrink the cache above this share of the limit
+	_memoryLowWater      = 0.7 // grow the cache back below this share
+)
+
+var _memoryMetrics = []string{
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// WithMemoryLimit shrinks the LRU cache while the memory used by the Go
+// runtime is close to limit bytes, or to GOMEMLIMIT if that is lower, and
+// grows it back towards WithMaxCacheSize once the pressure is gone.
+func WithMemoryLimit(limit int64) Option {
+	return func(o *option) error {
+		o.memoryLimit = limit
+		return nil
+	}
+}
+
+// ErrMemoryBudget is returned by ephemeral writes that would take the
+// store over its memory budget.
+var ErrMemoryBudget = errors.New("memory budget exceeded")
+
+// WithTotalMemoryBudget caps the bytes of keys and values held in memory
+// by the store, not counting bookkeeping overhead. The LRU cache, enabled
+// by it if needed, gets what the in-memory overlay of WithEphemeralWrites
+// leaves and shrinks as the overlay grows; writes that would take the
+// overlay itself over the budget fail with ErrMemoryBudget.
+// WithMaxCacheBytes still caps the cache within the budget.
+func WithTotalMemoryBudget(n int64) Option {
+	return func(o *option) error {
+		o.memoryBudget = n
+		return nil
+	}
+}
+
+// MemoryStats are the bytes held in memory by the store.
+type MemoryStats struct {
+	// Budget is set by WithTotalMemoryBudget, zero for none.
+	Budget  int64
+	Cache   int64
+	Overlay int64
+}
+
+// MemoryStats returns the bytes held in memory by the store.
+func (s *Store) MemoryStats() MemoryStats {
+	return MemoryStats{
+		Budget:  s.opt.Load().memoryBudget,
+		Cache:   s.CacheStats().Bytes,
+		Overlay: s.overlay.size(),
+	}
+}
+
+// overBudget reports whether adding n bytes to the overlay would exceed
+// the memory budget
+func (s *Store) overBudget(n int) bool {
+	budget := s.opt.Load().memoryBudget
+	return budget > 0 && s.overlay.size()+int64(n) > budget
+}
+
+// rebalanceMemory gives the cache the part of the memory budget the
+// overlay leaves
+func (s *Store) rebalanceMemory() {
+	if s.opt.Load().memoryBudget <= 0 || s.lru == nil {
+		return
+	}
+	s.lru.SetMaxBytes(s.cacheMaxBytes())
+}
+
+// cacheCapacity returns the maximum number of items in the LRU cache,
+// unbounded when WithMaxCacheSize is not set
+func cacheCapacity(opt *option) int {
+	if opt.maxCacheSize <= 0 {
+		return math.MaxInt
+	}
+	return opt.maxCacheSize
+}
+
+// cacheMaxBytes returns the byte limit of the LRU cache: WithMaxCacheBytes
+// within what the overlay leaves of the memory budget, zero for none
+func (s *Store) cacheMaxBytes() int64 {
+	opt := s.opt.Load()
+	limit := opt.maxCacheBytes
+	if opt.memoryBudget > 0 {
+		left := max(opt.memoryBudget-s.overlay.size(), 1)
+		if limit <= 0 || left < limit {
+			limit = left
+		}
+	}
+	return limit
+}
+
+func (s *Store) runMemoryMonitor() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(_memoryCheckInterval)
+	defer ticker.Stop()
+	samples := make([]metrics.Sample, len(_memoryMetrics))
+	for i, name := range _memoryMetrics {
+		samples[i].Name = name
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			metrics.Read(samples)
+			used := int64(samples[0].Value.Uint64() - samples[1].Value.Uint64())
+			limit := s.opt.Load().memoryLimit
+			if limit <= 0 {
+				continue
+			}
+			if goLimit := debug.SetMemoryLimit(-1); goLimit < limit {
+				limit = goLimit
+			}
+			s.adjustCacheSize(used, limit)
+		}
+	}
+}
+
+// adjustCacheSize halves the cache under memory pressure and grows it by a
+// quarter when there is room again, up to its capacity set by the options.
+// A cache only limited in bytes has its byte limit adjusted instead.
+func (s *Store) adjustCacheSize(used, limit int64) {
+	opt := s.opt.Load()
+	if opt.maxCacheSize <= 0 {
+		s.adjustCacheBytes(used, limit)
+		return
+	}
+	size, maxSize := s.lru.Cap(), cacheCapacity(opt)
+	switch {
+	case float64(used) > float64(limit)*_memoryHighWater:
+		if size > 1 {
+			s.lru.Resize(size / 2)
+		}
+	case float64(used) < float64(limit)*_memoryLowWater:
+		if size < maxSize {
+			s.lru.Resize(min(size+size/4+1, maxSize))
+		}
+	}
+}
+
+// adjustCacheBytes is adjustCacheSize for the byte limit of the cache
+func (s *Store) adjustCacheBytes(used, limit int64) {
+	n, maxBytes := s.lru.MaxBytes(), s.cacheMaxBytes()
+	switch {
+	case float64(used) > float64(limit)*_memoryHighWater:
+		if n > 1 {
+			s.lru.SetMaxBytes(n / 2)
+		}
+	case float64(used) < float64(limit)*_memoryLowWater:
+		if n < maxBytes {
+			s.lru.SetMaxBytes(min(n+n/4+1, maxBytes))
+		}
+	}
+}
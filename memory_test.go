@@ -0,0 +1,106 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestAdjustCacheSize(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(100), WithMemoryLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.adjustCacheSize(950, 1000)
+	if s.lru.Cap() != 50 {
+		t.Errorf("expected cache size %d, got %d", 50, s.lru.Cap())
+	}
+	s.adjustCacheSize(800, 1000)
+	if s.lru.Cap() != 50 {
+		t.Errorf("expected cache size %d, got %d", 50, s.lru.Cap())
+	}
+	for i := 0; i < 10; i++ {
+		s.adjustCacheSize(100, 1000)
+	}
+	if s.lru.Cap() != 100 {
+		t.Errorf("expected cache size %d, got %d", 100, s.lru.Cap())
+	}
+}
+
+func TestAdjustCacheBytes(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheBytes(1000), WithMemoryLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.adjustCacheSize(950, 1000)
+	if s.lru.MaxBytes() != 500 {
+		t.Errorf("expected cache bytes %d, got %d", 500, s.lru.MaxBytes())
+	}
+	for i := 0; i < 10; i++ {
+		s.adjustCacheSize(100, 1000)
+	}
+	if s.lru.MaxBytes() != 1000 {
+		t.Errorf("expected cache bytes %d, got %d", 1000, s.lru.MaxBytes())
+	}
+}
+
+func TestTotalMemoryBudget(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(path, WithReadOnly(), WithEphemeralWrites(), WithTotalMemoryBudget(1000), WithCacheNamespaces("ns"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.lru == nil {
+		t.Fatal("expected the budget to enable the cache")
+	}
+	value := make([]byte, 100)
+	for i := 0; i < 5; i++ {
+		if err := s.Put("ns", []byte(fmt.Sprint("key", i)), value); err != nil {
+			t.Error(err)
+		}
+	}
+	stats := s.MemoryStats()
+	if stats.Budget != 1000 || stats.Overlay == 0 || stats.Cache == 0 {
+		t.Errorf("expected overlay and cache bytes got %+v", stats)
+	}
+	if stats.Cache+stats.Overlay > 1000 {
+		t.Errorf("expected at most 1000 bytes got %+v", stats)
+	}
+	var err2 error
+	for i := 5; i < 20 && err2 == nil; i++ {
+		err2 = s.Put("ns", []byte(fmt.Sprint("key", i)), value)
+	}
+	if !errors.Is(err2, ErrMemoryBudget) {
+		t.Errorf("expected error %s, got %v", ErrMemoryBudget, err2)
+	}
+	stats = s.MemoryStats()
+	if stats.Cache+stats.Overlay > 1000 {
+		t.Errorf("expected at most 1000 bytes got %+v", stats)
+	}
+}
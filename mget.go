@@ -0,0 +1,41 @@
+package gostore
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MGet returns the values of keys in a single read transaction, in the
+// order of keys. Missing and expired keys have a nil value.
+func (s *Store) MGet(namespace []byte, keys ...[]byte) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		now := time.Now()
+		for i, key := range s.diskKeys(keys) {
+			v, ok := s.overlay.get(namespace, key)
+			if !ok && bucket != nil {
+				v = bucket.Get(key)
+			}
+			if v == nil {
+				continue
+			}
+			var value valueT
+			if err := value.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("key %s: %w: %w", key, ErrCorrupted, err)
+			}
+			if !value.Expire.IsZero() && now.After(value.Expire) {
+				s.notifyExpired(namespace, key, value.Expire)
+				continue
+			}
+			values[i] = value.Value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
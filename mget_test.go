@@ -0,0 +1,49 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMGet(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("ns")
+	if err := s.Put(string(ns), []byte("a"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL(ns, []byte("b"), []byte("2"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("c"), []byte("3")); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+
+	values, err := s.MGet(ns, []byte("c"), []byte("b"), []byte("missing"), []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"3", "", "", "1"}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values got %d", len(expected), len(values))
+	}
+	for i, v := range values {
+		if string(v) != expected[i] {
+			t.Errorf("expected %q got %q", expected[i], v)
+		}
+	}
+	if values, err := s.MGet([]byte("none"), []byte("a")); err != nil || values[0] != nil {
+		t.Errorf("expected nil value got %q, %v", values, err)
+	}
+}
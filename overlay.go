@@ -0,0 +1,264 @@
+package gostore
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// WithEphemeralWrites lets a read-only store accept Put, PutWithTTL,
+// Delete and the Update and Memoize calls built on them. The writes go to
+// an in-memory overlay seen by Get, GetView, Load and LoadMany, and are
+// discarded on Close; the database file is never written. Other writes
+// still fail as read-only. Immutable, no-overwrite and quota checks see
+// the overlaid records over those of the file; a quota with
+// QuotaEvictOldest rejects the write instead, as nothing is evicted. It
+// has no effect without WithReadOnly.
+func WithEphemeralWrites() Option {
+	return func(o *option) error {
+		o.ephemeralWrites = true
+		return nil
+	}
+}
+
+// overlay holds the encoded records written to a read-only store, with nil
+// marking a deleted key
+type overlay struct {
+	// writeMu serializes the checks and writes of putOverlay and
+	// deleteOverlay
+	writeMu sync.Mutex
+	mu      sync.RWMutex
+	records map[string][]byte
+	// bytes is the size of the keys and records held
+	bytes int64
+}
+
+func newOverlay() *overlay {
+	return &overlay{records: make(map[string][]byte)}
+}
+
+// overlayKey joins namespace and key so no two pairs collide
+func overlayKey(namespace, key []byte) string {
+	return string(namespace) + "\x00" + string(key)
+}
+
+func (o *overlay) put(namespace, key, buf []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	k := overlayKey(namespace, key)
+	if old, ok := o.records[k]; ok {
+		o.bytes -= int64(len(k) + len(old))
+	}
+	o.records[k] = buf
+	o.bytes += int64(len(k) + len(buf))
+}
+
+// size returns the bytes held by the overlay
+func (o *overlay) size() int64 {
+	if o == nil {
+		return 0
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.bytes
+}
+
+func (o *overlay) delete(namespace, key []byte) {
+	o.put(namespace, key, nil)
+}
+
+// get returns the overlaid record of key, and whether the overlay has
+// one. A nil record means the key was deleted.
+func (o *overlay) get(namespace, key []byte) ([]byte, bool) {
+	if o == nil {
+		return nil, false
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	buf, ok := o.records[overlayKey(namespace, key)]
+	return buf, ok
+}
+
+// namespace returns the overlaid records of namespace by key, nil marking
+// deleted keys
+func (o *overlay) namespace(namespace []byte) map[string][]byte {
+	if o == nil {
+		return nil
+	}
+	prefix := overlayKey(namespace, nil)
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var records map[string][]byte
+	for k, buf := range o.records {
+		if key, ok := strings.CutPrefix(k, prefix); ok {
+			if records == nil {
+				records = make(map[string][]byte)
+			}
+			records[key] = buf
+		}
+	}
+	return records
+}
+
+// namespaces returns the namespaces with overlaid records that are not
+// deleted
+func (o *overlay) namespaces() []string {
+	if o == nil {
+		return nil
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var names []string
+	for k, buf := range o.records {
+		if buf != nil {
+			ns, _, _ := strings.Cut(k, "\x00")
+			names = append(names, ns)
+		}
+	}
+	return names
+}
+
+// putOverlay writes an encoded record to the overlay after the checks put
+// runs against the database, the current record being the overlaid one or
+// else the one in the file
+func (s *Store) putOverlay(namespace, key, buf []byte) (err error) {
+	if isInternal(string(namespace)) {
+		return ErrInternalNamespace
+	}
+	hooks := s.opt.Load().hooks
+	if hooks.BeforePut != nil {
+		if err := hooks.BeforePut(namespace, key, recordValue(buf)); err != nil {
+			return err
+		}
+	}
+	if hooks.AfterPut != nil {
+		defer func() { hooks.AfterPut(namespace, key, recordValue(buf), err) }()
+	}
+	if s.isFrozen(namespace) {
+		return ErrFrozen
+	}
+	s.overlay.writeMu.Lock()
+	defer s.overlay.writeMu.Unlock()
+	old, err := s.overlayRecord(namespace, key)
+	if err != nil {
+		return err
+	}
+	if s.isImmutable(namespace) && old != nil {
+		return ErrImmutable
+	}
+	if err := s.checkOverwrite(namespace, old); err != nil {
+		return err
+	}
+	if err := s.checkOverlayQuota(namespace, key, old, buf); err != nil {
+		return err
+	}
+	if s.overBudget(len(overlayKey(namespace, key)) + len(buf)) {
+		return ErrMemoryBudget
+	}
+	s.overlay.put(namespace, key, buf)
+	s.rebalanceMemory()
+	return nil
+}
+
+// deleteOverlay marks key deleted in the overlay after the checks delete
+// runs against the database
+func (s *Store) deleteOverlay(namespace, key []byte) error {
+	if isInternal(string(namespace)) {
+		return ErrInternalNamespace
+	}
+	if s.isFrozen(namespace) {
+		return ErrFrozen
+	}
+	s.overlay.writeMu.Lock()
+	defer s.overlay.writeMu.Unlock()
+	if s.isImmutable(namespace) {
+		if old, err := s.overlayRecord(namespace, key); err != nil {
+			return err
+		} else if old != nil {
+			return ErrImmutable
+		}
+	}
+	s.overlay.delete(namespace, key)
+	s.rebalanceMemory()
+	return nil
+}
+
+// overlayRecord returns the current record of key: the overlaid one if
+// any, else a copy of the one in the file, nil if there is none
+func (s *Store) overlayRecord(namespace, key []byte) ([]byte, error) {
+	if buf, ok := s.overlay.get(namespace, key); ok {
+		return buf, nil
+	}
+	var old []byte
+	err := s.view(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(namespace); bucket != nil {
+			old = bytes.Clone(bucket.Get(key))
+		}
+		return nil
+	})
+	return old, err
+}
+
+// checkOverlayQuota is checkQuota for the overlay: the usage of the
+// namespace is that of the file with the overlaid records applied
+func (s *Store) checkOverlayQuota(namespace, key, old, buf []byte) error {
+	q := s.quotas[string(namespace)]
+	if q == nil {
+		return nil
+	}
+	next, err := s.overlayUsage(q, namespace)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		next.Keys++
+	} else {
+		next.Bytes -= int64(len(key) + len(old))
+	}
+	next.Bytes += int64(len(key) + len(buf))
+	if !q.exceeded(next) {
+		return nil
+	}
+	if q.Policy == QuotaCallback {
+		if q.OnExceeded != nil {
+			return q.OnExceeded(string(namespace), next)
+		}
+		return nil
+	}
+	return ErrQuotaExceeded
+}
+
+// overlayUsage returns the usage of a namespace in the file with the
+// overlaid records applied
+func (s *Store) overlayUsage(q *quotaState, namespace []byte) (Usage, error) {
+	var u Usage
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket != nil {
+			u = q.current(tx, bucket)
+		}
+		prefix := overlayKey(namespace, nil)
+		s.overlay.mu.RLock()
+		defer s.overlay.mu.RUnlock()
+		for k, buf := range s.overlay.records {
+			key, ok := bytes.CutPrefix([]byte(k), []byte(prefix))
+			if !ok {
+				continue
+			}
+			if bucket != nil {
+				if v := bucket.Get(key); v != nil {
+					u.Keys--
+					u.Bytes -= int64(len(key) + len(v))
+				}
+			}
+			if buf != nil {
+				u.Keys++
+				u.Bytes += int64(len(key) + len(buf))
+			}
+		}
+		return nil
+	})
+	return u, err
+}
@@ -0,0 +1,115 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestEphemeralWrites(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := []byte("ns")
+	if err := s.Put(string(ns), []byte("a"), []byte("disk")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("b"), []byte("disk")); err != nil {
+		t.Error(err)
+	}
+	s.Close()
+
+	s, err = Open(path, WithReadOnly(), WithEphemeralWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(string(ns), []byte("a"), []byte("overlay")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete(string(ns), []byte("b")); err != nil {
+		t.Error(err)
+	}
+	if v, err := s.Get(ns, []byte("a")); err != nil || string(v) != "overlay" {
+		t.Errorf("expected overlay got %s, %v", v, err)
+	}
+	if _, err := s.Get(ns, []byte("b")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	if _, err := s.PutNX(ns, []byte("c"), []byte("v"), 0); err == nil {
+		t.Error("expected read-only error")
+	}
+	s.Close()
+
+	s, err = Open(path, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, key := range []string{"a", "b"} {
+		if v, err := s.Get(ns, []byte(key)); err != nil || string(v) != "disk" {
+			t.Errorf("expected disk got %s, %v", v, err)
+		}
+	}
+}
+
+func TestEphemeralWritesChecks(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"imm", "nox", "quota"} {
+		if err := s.Put(ns, []byte("a"), []byte("disk")); err != nil {
+			t.Error(err)
+		}
+	}
+	s.Close()
+
+	s, err = Open(path, WithReadOnly(), WithEphemeralWrites(),
+		WithImmutableNamespace("imm"), WithNoOverwrite("nox"),
+		WithNamespaceQuota("quota", Quota{MaxKeys: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Put("imm", []byte("a"), []byte("overlay")); !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %v", ErrImmutable, err)
+	}
+	if err := s.Delete("imm", []byte("a")); !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %v", ErrImmutable, err)
+	}
+	if err := s.Put("nox", []byte("a"), []byte("overlay")); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("expected error %s, got %v", ErrKeyExists, err)
+	}
+	if err := s.Put("nox", []byte("b"), []byte("overlay")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("nox", []byte("b"), []byte("again")); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("expected error %s, got %v", ErrKeyExists, err)
+	}
+	if err := s.Put("quota", []byte("b"), []byte("overlay")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("quota", []byte("c"), []byte("overlay")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected error %s, got %v", ErrQuotaExceeded, err)
+	}
+	if err := s.Delete("quota", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("quota", []byte("c"), []byte("overlay")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(CorruptNamespace, []byte("a"), []byte("overlay")); !errors.Is(err, ErrInternalNamespace) {
+		t.Errorf("expected error %s, got %v", ErrInternalNamespace, err)
+	}
+}
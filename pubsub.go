@@ -0,0 +1,180 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_topicPrefix     = _internalPrefix + "topic:"
+	_offsetsBucket   = _internalPrefix + "offsets"
+	_defaultTopicCap = 10000
+)
+
+// TopicMessage is a message published to a topic
+type TopicMessage struct {
+	Seq   uint64
+	Topic string
+	Data  []byte
+}
+
+// topics wakes up the subscribers of a topic after a publish
+type topics struct {
+	mu      sync.Mutex
+	waiting map[string]chan struct{}
+}
+
+// wait returns a channel closed at the next publish to topic
+func (t *topics) wait(topic string) <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ch, ok := t.waiting[topic]
+	if !ok {
+		if t.waiting == nil {
+			t.waiting = make(map[string]chan struct{})
+		}
+		ch = make(chan struct{})
+		t.waiting[topic] = ch
+	}
+	return ch
+}
+
+func (t *topics) notify(topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ch, ok := t.waiting[topic]; ok {
+		close(ch)
+		delete(t.waiting, topic)
+	}
+}
+
+// WithTopicCap keeps at most maxMessages messages per topic, dropping the
+// oldest ones. Zero keeps the default of 10000, a negative cap all of them.
+func WithTopicCap(maxMessages int) Option {
+	return func(o *option) error {
+		o.topicCap = maxMessages
+		return nil
+	}
+}
+
+// Publish appends a message to a topic and returns its sequence number.
+// Sequence numbers of a topic start at 1 and increase with every message.
+func (s *Store) Publish(topic string, msg []byte) (seq uint64, err error) {
+	err = s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(_topicPrefix + topic))
+		if err != nil {
+			return err
+		}
+		if seq, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+		if err := bucket.Put(seqKey(seq), msg); err != nil {
+			return err
+		}
+		limit := s.opt.Load().topicCap
+		if limit == 0 {
+			limit = _defaultTopicCap
+		}
+		if limit > 0 {
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && seq-binary.BigEndian.Uint64(k) >= uint64(limit); k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		tx.OnCommit(func() { s.topics.notify(topic) })
+		return nil
+	})
+	return seq, err
+}
+
+// Subscribe sends the messages of a topic with a sequence number greater
+// than fromSeq to the returned channel, oldest first, and then every new
+// message as it is published. Both channels are closed when ctx is done or
+// the store is closed; the error channel receives at most one error. Pass
+// the Offset of a consumer to resume where it stopped.
+func (s *Store) Subscribe(ctx context.Context, topic string, fromSeq uint64) (<-chan TopicMessage, <-chan error) {
+	msgs := make(chan TopicMessage)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(msgs)
+		for {
+			// wait before reading, so no publish is missed in between
+			wake := s.topics.wait(topic)
+			var batch []TopicMessage
+			if err := s.view(func(tx *bolt.Tx) error {
+				bucket := tx.Bucket([]byte(_topicPrefix + topic))
+				if bucket == nil {
+					return nil
+				}
+				c := bucket.Cursor()
+				for k, v := c.Seek(seqKey(fromSeq + 1)); k != nil && len(batch) < _defaultChunkSize; k, v = c.Next() {
+					batch = append(batch, TopicMessage{Seq: binary.BigEndian.Uint64(k), Topic: topic, Data: bytes.Clone(v)})
+				}
+				return nil
+			}); err != nil {
+				errc <- err
+				return
+			}
+			for _, msg := range batch {
+				select {
+				case msgs <- msg:
+					fromSeq = msg.Seq
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+			}
+			if len(batch) == _defaultChunkSize {
+				continue
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return msgs, errc
+}
+
+// CommitOffset records seq as the last message of a topic processed by
+// consumer
+func (s *Store) CommitOffset(topic, consumer string, seq uint64) error {
+	return s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(_offsetsBucket))
+		if err != nil {
+			return err
+		}
+		return bucket.Put(offsetKey(topic, consumer), seqKey(seq))
+	})
+}
+
+// Offset returns the offset of consumer committed with CommitOffset, zero
+// if there is none
+func (s *Store) Offset(topic, consumer string) (seq uint64, err error) {
+	err = s.view(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(_offsetsBucket)); bucket != nil {
+			if v := bucket.Get(offsetKey(topic, consumer)); len(v) == 8 {
+				seq = binary.BigEndian.Uint64(v)
+			}
+		}
+		return nil
+	})
+	return seq, err
+}
+
+func offsetKey(topic, consumer string) []byte {
+	k := binary.AppendUvarint(nil, uint64(len(topic)))
+	k = append(k, topic...)
+	return append(k, consumer...)
+}
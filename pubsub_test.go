@@ -0,0 +1,65 @@
+package gostore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPubSub(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithTopicCap(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 1; i <= 4; i++ {
+		seq, err := s.Publish("events", []byte(fmt.Sprint(i)))
+		if err != nil || seq != uint64(i) {
+			t.Errorf("expected seq %d got %d, %v", i, seq, err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgs, errc := s.Subscribe(ctx, "events", 0)
+	// the first message was dropped by the cap
+	for want := uint64(2); want <= 5; want++ {
+		if want == 5 {
+			if _, err := s.Publish("events", []byte("5")); err != nil {
+				t.Error(err)
+			}
+		}
+		select {
+		case msg := <-msgs:
+			if msg.Seq != want || string(msg.Data) != fmt.Sprint(want) || msg.Topic != "events" {
+				t.Errorf("expected message %d got %+v", want, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected message %d", want)
+		}
+	}
+
+	if err := s.CommitOffset("events", "worker", 5); err != nil {
+		t.Error(err)
+	}
+	if seq, err := s.Offset("events", "worker"); err != nil || seq != 5 {
+		t.Errorf("expected offset 5 got %d, %v", seq, err)
+	}
+	if seq, err := s.Offset("events", "other"); err != nil || seq != 0 {
+		t.Errorf("expected offset 0 got %d, %v", seq, err)
+	}
+
+	cancel()
+	for range msgs {
+	}
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,214 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrQuotaExceeded is returned when a write would take a namespace past its
+// quota.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaPolicy decides what happens when a write would exceed a quota.
+type QuotaPolicy uint8
+
+const (
+	// QuotaReject fails the write with ErrQuotaExceeded.
+	QuotaReject QuotaPolicy = iota
+	// QuotaEvictOldest deletes the lowest keys of the namespace until the
+	// write fits. With time-ordered keys these are the oldest records.
+	QuotaEvictOldest
+	// QuotaCallback calls Quota.OnExceeded and fails the write if it
+	// returns an error.
+	QuotaCallback
+)
+
+// Quota limits the number of keys and bytes stored in a namespace. A zero
+// limit means unlimited.
+type Quota struct {
+	MaxKeys  int
+	MaxBytes int64
+	Policy   QuotaPolicy
+	// OnExceeded is called with the usage the write would reach. It runs
+	// inside the write transaction, which holds the writer lock of the
+	// store, so it must not call the Store: its writes would wait for that
+	// lock forever. Hand such work to another goroutine.
+	OnExceeded func(namespace string, usage Usage) error
+}
+
+// Usage is the number of keys and bytes, keys included, stored in a
+// namespace.
+type Usage struct {
+	Keys  int
+	Bytes int64
+}
+
+// WithNamespaceQuota sets a soft quota on a namespace
+func WithNamespaceQuota(namespace string, q Quota) Option {
+	return func(o *option) error {
+		if o.quotas == nil {
+			o.quotas = make(map[string]Quota)
+		}
+		o.quotas[namespace] = q
+		return nil
+	}
+}
+
+// quotaState tracks the usage of a namespace with a quota. Usage is counted
+// once on first write and kept up to date as transactions commit.
+type quotaState struct {
+	Quota
+	mu     sync.Mutex
+	loaded bool
+	usage  Usage
+	// pending holds the changes of the running write transactions,
+	// applied to usage as they commit
+	pending map[*bolt.Tx]*Usage
+}
+
+func newQuotaStates(quotas map[string]Quota) map[string]*quotaState {
+	if len(quotas) == 0 {
+		return nil
+	}
+	states := make(map[string]*quotaState, len(quotas))
+	for ns, q := range quotas {
+		states[ns] = &quotaState{Quota: q}
+	}
+	return states
+}
+
+func (q *quotaState) exceeded(u Usage) bool {
+	return (q.MaxKeys > 0 && u.Keys > q.MaxKeys) ||
+		(q.MaxBytes > 0 && u.Bytes > q.MaxBytes)
+}
+
+// current returns the usage seen by tx: the committed usage, counting the
+// bucket if this is the first write since Open, plus the changes tx made.
+func (q *quotaState) current(tx *bolt.Tx, bucket *bolt.Bucket) Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loaded {
+		var u Usage
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			u.Keys++
+			u.Bytes += int64(len(k) + len(v))
+		}
+		if p := q.pending[tx]; p != nil {
+			// the bucket already holds the changes of tx
+			u.Keys -= p.Keys
+			u.Bytes -= p.Bytes
+		}
+		q.usage, q.loaded = u, true
+	}
+	u := q.usage
+	if p := q.pending[tx]; p != nil {
+		u.Keys += p.Keys
+		u.Bytes += p.Bytes
+	}
+	return u
+}
+
+// add records a change of usage made by tx, applied once tx commits
+func (q *quotaState) add(tx *bolt.Tx, keys int, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p := q.pending[tx]
+	if p == nil {
+		if q.pending == nil {
+			q.pending = make(map[*bolt.Tx]*Usage)
+		}
+		p = &Usage{}
+		q.pending[tx] = p
+		tx.OnCommit(func() { q.commit(tx) })
+	}
+	p.Keys += keys
+	p.Bytes += size
+}
+
+// commit applies the changes of tx to the usage
+func (q *quotaState) commit(tx *bolt.Tx) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if p := q.pending[tx]; p != nil {
+		q.usage.Keys += p.Keys
+		q.usage.Bytes += p.Bytes
+		delete(q.pending, tx)
+	}
+}
+
+// discard drops the changes of a transaction that did not commit
+func (q *quotaState) discard(tx *bolt.Tx) {
+	q.mu.Lock()
+	delete(q.pending, tx)
+	q.mu.Unlock()
+}
+
+// discardQuotas drops the pending quota changes of a finished write
+// transaction
+func (s *Store) discardQuotas(tx *bolt.Tx) {
+	for _, q := range s.quotas {
+		q.discard(tx)
+	}
+}
+
+func (q *quotaState) reset() {
+	q.mu.Lock()
+	q.usage, q.loaded = Usage{}, false
+	q.mu.Unlock()
+}
+
+// checkQuota makes room for key in bucket according to the namespace quota.
+// It returns the keys evicted to make room.
+func (s *Store) checkQuota(tx *bolt.Tx, bucket *bolt.Bucket, namespace, key, buf []byte) ([][]byte, error) {
+	q := s.quotas[string(namespace)]
+	if q == nil {
+		return nil, nil
+	}
+	before := q.current(tx, bucket)
+	next := before
+	if old := bucket.Get(key); old == nil {
+		next.Keys++
+	} else {
+		next.Bytes -= int64(len(key) + len(old))
+	}
+	next.Bytes += int64(len(key) + len(buf))
+
+	var evicted [][]byte
+	for q.exceeded(next) {
+		if q.Policy == QuotaCallback {
+			if q.OnExceeded != nil {
+				if err := q.OnExceeded(string(namespace), next); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+		if q.Policy != QuotaEvictOldest || s.isImmutable(namespace) {
+			return nil, ErrQuotaExceeded
+		}
+		c := bucket.Cursor()
+		k, v := c.First()
+		if k != nil && bytes.Equal(k, key) {
+			k, v = c.Next()
+		}
+		if k == nil {
+			return nil, ErrQuotaExceeded
+		}
+		next.Keys--
+		next.Bytes -= int64(len(k) + len(v))
+		k = append([]byte(nil), k...)
+		evicted = append(evicted, k)
+		if err := c.Delete(); err != nil {
+			return nil, err
+		}
+		if err := s.changed(tx, OpDelete, namespace, k, nil); err != nil {
+			return nil, err
+		}
+	}
+	q.add(tx, next.Keys-before.Keys, next.Bytes-before.Bytes)
+	return evicted, nil
+}
@@ -0,0 +1,158 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestQuotaReject(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithNamespaceQuota("test", Quota{MaxKeys: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"a", "b"} {
+		if err := s.Put("test", []byte(k), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.Put("test", []byte("c"), []byte("value")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected error %s, got %s", ErrQuotaExceeded, err)
+	}
+	// overwriting an existing key does not add a key
+	if err := s.Put("test", []byte("a"), []byte("value2")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete("test", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("test", []byte("c"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	// other namespaces are not limited
+	for _, k := range []string{"a", "b", "c"} {
+		if err := s.Put("other", []byte(k), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestQuotaWithinTransaction(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithNamespaceQuota("test", Quota{MaxKeys: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	kvs := []KV{
+		{Key: []byte("a"), Value: []byte("1")}, {Key: []byte("b"), Value: []byte("2")},
+		{Key: []byte("c"), Value: []byte("3")}, {Key: []byte("d"), Value: []byte("4")},
+	}
+	if n, err := s.PutBatch("test", kvs); !errors.Is(err, ErrQuotaExceeded) || n != 0 {
+		t.Errorf("expected error %s and 0 written, got %v and %d", ErrQuotaExceeded, err, n)
+	}
+	err = s.Tx(func(tx *StoreTx) error {
+		for _, kv := range kvs {
+			if err := tx.PutWithTTL([]byte("test"), kv.Key, kv.Value, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected error %s, got %v", ErrQuotaExceeded, err)
+	}
+	// a delete in the transaction makes room for another key
+	err = s.Tx(func(tx *StoreTx) error {
+		for _, kv := range kvs[:2] {
+			if err := tx.PutWithTTL([]byte("test"), kv.Key, kv.Value, 0); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete([]byte("test"), []byte("a")); err != nil {
+			return err
+		}
+		return tx.PutWithTTL([]byte("test"), []byte("c"), []byte("3"), 0)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("test", []byte("d"), []byte("4")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected error %s, got %v", ErrQuotaExceeded, err)
+	}
+	keys, _, err := s.Keys([]byte("test"), nil, 0)
+	if err != nil || len(keys) != 2 {
+		t.Errorf("expected 2 keys, got %d, %v", len(keys), err)
+	}
+}
+
+func TestQuotaEvictOldest(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithNamespaceQuota("test", Quota{MaxKeys: 2, Policy: QuotaEvictOldest}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"1", "2", "3"} {
+		if err := s.Put("test", []byte(k), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := s.Get([]byte("test"), []byte("1")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %s", ErrKeyNotFound, err)
+	}
+	for _, k := range []string{"2", "3"} {
+		if _, err := s.Get([]byte("test"), []byte(k)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestQuotaCallback(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	errFull := errors.New("full")
+	var got Usage
+	s, err := Open(path, WithNamespaceQuota("test", Quota{
+		MaxBytes: 32,
+		Policy:   QuotaCallback,
+		OnExceeded: func(namespace string, usage Usage) error {
+			got = usage
+			return errFull
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("test", []byte("a"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("test", []byte("b"), []byte("value")); !errors.Is(err, errFull) {
+		t.Errorf("expected error %s, got %s", errFull, err)
+	}
+	if got.Keys != 2 {
+		t.Errorf("expected %d keys, got %d", 2, got.Keys)
+	}
+}
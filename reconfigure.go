@@ -0,0 +1,96 @@
+package gostore
+
+import "errors"
+
+// ErrNotReconfigurable is returned by Reconfigure for a change that needs
+// the store to be reopened.
+var ErrNotReconfigurable = errors.New("option cannot be changed at runtime")
+
+// Reconfigure changes the options of an open store. Only the cache size in
+// items and bytes, memory limit, GC interval and pacing, number of retries,
+// idempotency TTL and maximum transaction size can change at runtime; other
+// options are ignored. The cache size can only be changed if the cache was
+// enabled at Open.
+func (s *Store) Reconfigure(opts ...Option) error {
+	s.reconfigMu.Lock()
+	defer s.reconfigMu.Unlock()
+
+	cur := s.opt.Load()
+	req := *cur
+	// these cannot change; keep them from writing into the live ones
+	req.quotas, req.immutable, req.webhooks, req.cacheNamespaces = nil, nil, nil, nil
+	req.noOverwrite = nil
+	for _, o := range opts {
+		if err := o(&req); err != nil {
+			return err
+		}
+	}
+	if req.maxCacheSize != cur.maxCacheSize && (s.lru == nil || req.maxCacheSize <= 0) {
+		return ErrNotReconfigurable
+	}
+	if req.maxCacheBytes != cur.maxCacheBytes && s.lru == nil {
+		return ErrNotReconfigurable
+	}
+	if req.numRetries == 0 {
+		req.numRetries = _defaultNumRetries
+	}
+	if req.readRetries == 0 {
+		req.readRetries = _defaultNumRetries
+	}
+
+	next := *cur
+	next.maxCacheSize = req.maxCacheSize
+	next.maxCacheBytes = req.maxCacheBytes
+	next.memoryLimit = req.memoryLimit
+	next.gcInterval = req.gcInterval
+	next.gcPacing = req.gcPacing
+	next.numRetries = req.numRetries
+	next.readRetries = req.readRetries
+	next.idempotencyTTL = req.idempotencyTTL
+	next.maxTxSize = req.maxTxSize
+	s.opt.Store(&next)
+
+	if next.maxCacheSize != cur.maxCacheSize {
+		s.lru.Resize(cacheCapacity(&next))
+	}
+	if next.maxCacheBytes != cur.maxCacheBytes {
+		s.lru.SetMaxBytes(next.maxCacheBytes)
+		s.rebalanceMemory()
+	}
+	if next.gcInterval != cur.gcInterval {
+		select {
+		case s.gcReset <- struct{}{}:
+		default:
+		}
+	}
+	s.startBackground()
+	return nil
+}
+
+// startBackground starts the goroutines the current options need. Each one
+// is started at most once and picks up later changes by itself.
+func (s *Store) startBackground() {
+	opt := s.opt.Load()
+	s.bgMu.Lock()
+	defer s.bgMu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	if opt.gcInterval > 0 && !opt.readOnly && !s.gcRunning {
+		s.gcRunning = true
+		s.wg.Add(1)
+		go s.runGC()
+	}
+	if opt.autoCompact > 0 && !opt.readOnly && !s.compacting {
+		s.compacting = true
+		s.wg.Add(1)
+		go s.runAutoCompact()
+	}
+	if opt.memoryLimit > 0 && s.lru != nil && !s.memRunning {
+		s.memRunning = true
+		s.wg.Add(1)
+		go s.runMemoryMonitor()
+	}
+}
@@ -0,0 +1,57 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReconfigure(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := s.Update(k, &T1{Name: k}); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.Reconfigure(WithMaxCacheSize(2), WithGCInterval(100*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if s.lru.Len() != 2 {
+		t.Errorf("expected %d cached items, got %d", 2, s.lru.Len())
+	}
+
+	if err := s.PutWithTTL([]byte("test"), []byte("key"), []byte("value"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2200 * time.Millisecond)
+	if _, err := s.get([]byte("test"), []byte("key")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %s", ErrKeyNotFound, err)
+	}
+}
+
+func TestReconfigureNoCache(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Reconfigure(WithMaxCacheSize(2)); err != ErrNotReconfigurable {
+		t.Errorf("expected error %s, got %s", ErrNotReconfigurable, err)
+	}
+}
@@ -0,0 +1,226 @@
+package gostore
+
+import (
+	"encoding"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_replicaPollInterval = time.Second
+	_replicaLockTimeout  = 100 * time.Millisecond
+)
+
+// ErrNoReplica is returned when neither the primary file nor any snapshot
+// could be opened.
+var ErrNoReplica = errors.New("no readable replica")
+
+// ReplicaSet serves reads from the primary file, or from the newest
+// snapshot in a directory while the primary is locked by another process.
+// The primary is opened for each read and closed after it, so a writer
+// process can always take its lock back.
+type ReplicaSet struct {
+	primary string
+	dir     string
+	opts    []Option
+
+	mu sync.RWMutex
+	// store is the newest snapshot opened, nil if none
+	store    *Store
+	snapshot string
+	modTime  time.Time
+	// current is the primary or the snapshot, whichever serves reads
+	current string
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// OpenReplicaSet opens a read-only replica set. Newer snapshots that appear
+// in snapshotDir are picked up automatically.
+func OpenReplicaSet(primary string, snapshotDir string, opts ...Option) (*ReplicaSet, error) {
+	r := &ReplicaSet{
+		primary: primary,
+		dir:     snapshotDir,
+		opts:    append(slices.Clip(opts), WithReadOnly(), WithOpenTimeout(_replicaLockTimeout)),
+		done:    make(chan struct{}),
+	}
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.wg.Add(1)
+	go r.watch()
+	return r, nil
+}
+
+// Path returns the file currently serving reads.
+func (r *ReplicaSet) Path() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Get fetches a value by key from the current replica
+func (r *ReplicaSet) Get(namespace, key []byte) (value []byte, err error) {
+	err = r.read(func(s *Store) error {
+		value, err = s.Get(namespace, key)
+		return err
+	})
+	return value, err
+}
+
+// Load read value by key from the current replica
+func (r *ReplicaSet) Load(key string, obj encoding.BinaryUnmarshaler) error {
+	return r.read(func(s *Store) error {
+		return s.Load(key, obj)
+	})
+}
+
+// Close stops watching the snapshot directory and closes the current
+// replica. Calls after the first do nothing.
+func (r *ReplicaSet) Close() (err error) {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.store != nil {
+			err = r.store.Close()
+		}
+	})
+	return err
+}
+
+// read calls fn with the primary, opened for the call, or with the newest
+// snapshot if the primary is locked
+func (r *ReplicaSet) read(fn func(s *Store) error) error {
+	r.mu.RLock()
+	current := r.current
+	r.mu.RUnlock()
+	if current == r.primary {
+		s, err := r.openPrimary()
+		if err == nil {
+			defer s.Close()
+			return fn(s)
+		}
+		r.mu.Lock()
+		r.current = r.snapshot
+		r.mu.Unlock()
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.store == nil {
+		return ErrNoReplica
+	}
+	return fn(r.store)
+}
+
+// openPrimary opens the primary, failing with bolt.ErrTimeout if another
+// process holds its lock
+func (r *ReplicaSet) openPrimary() (*Store, error) {
+	// bolt creates missing files even in read-only mode, so check first
+	if _, err := os.Stat(r.primary); err != nil {
+		return nil, err
+	}
+	return Open(r.primary, r.opts...)
+}
+
+func (r *ReplicaSet) watch() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(_replicaPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			_ = r.refresh()
+		}
+	}
+}
+
+// refresh opens the newest snapshot if it is newer than the one held, and
+// serves reads from the primary if it can be opened, otherwise from that
+// snapshot.
+func (r *ReplicaSet) refresh() error {
+	r.mu.RLock()
+	modTime := r.modTime
+	r.mu.RUnlock()
+	for _, snap := range r.snapshots() {
+		if !snap.modTime.After(modTime) {
+			break
+		}
+		s, err := Open(snap.path, r.opts...)
+		if err != nil {
+			// half-written or corrupt, try an older one
+			continue
+		}
+		r.swap(s, snap.path, snap.modTime)
+		break
+	}
+
+	current := r.primary
+	s, err := r.openPrimary()
+	if err == nil {
+		err = s.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		current = r.snapshot
+	}
+	r.current = current
+	if current == "" {
+		if err != nil && !errors.Is(err, bolt.ErrTimeout) && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return ErrNoReplica
+	}
+	return nil
+}
+
+func (r *ReplicaSet) swap(s *Store, path string, modTime time.Time) {
+	r.mu.Lock()
+	old := r.store
+	r.store, r.snapshot, r.modTime = s, path, modTime
+	r.mu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+}
+
+type snapshotFile struct {
+	path    string
+	modTime time.Time
+}
+
+// snapshots lists regular files in the snapshot directory, newest first.
+func (r *ReplicaSet) snapshots() []snapshotFile {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil
+	}
+	files := make([]snapshotFile, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, snapshotFile{filepath.Join(r.dir, e.Name()), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	return files
+}
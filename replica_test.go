@@ -0,0 +1,155 @@
+package gostore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplicaSet(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "replica_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	primary := filepath.Join(dir, "primary.db")
+	snapDir := filepath.Join(dir, "snapshots")
+	if err := os.Mkdir(snapDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSnapshot := func(name, value string, modTime time.Time) {
+		path := filepath.Join(snapDir, name)
+		s, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Put("test", []byte("key"), []byte(value)); err != nil {
+			t.Error(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	writeSnapshot("1.db", "old", now.Add(-time.Hour))
+	writeSnapshot("2.db", "new", now.Add(-time.Minute))
+
+	// hold the primary open so it is locked
+	s, err := Open(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r, err := OpenReplicaSet(primary, snapDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Path() != filepath.Join(snapDir, "2.db") {
+		t.Errorf("expected newest snapshot, got %s", r.Path())
+	}
+	value, err := r.Get([]byte("test"), []byte("key"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(value) != "new" {
+		t.Errorf("expected value %s, got %s", "new", value)
+	}
+
+	writeSnapshot("3.db", "newest", now)
+	if err := r.refresh(); err != nil {
+		t.Error(err)
+	}
+	value, err = r.Get([]byte("test"), []byte("key"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(value) != "newest" {
+		t.Errorf("expected value %s, got %s", "newest", value)
+	}
+
+	// the primary serves reads once unlocked, without holding its lock
+	if err := s.Put("test", []byte("key"), []byte("primary")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.refresh(); err != nil {
+		t.Error(err)
+	}
+	if r.Path() != primary {
+		t.Errorf("expected primary, got %s", r.Path())
+	}
+	value, err = r.Get([]byte("test"), []byte("key"))
+	if err != nil || string(value) != "primary" {
+		t.Errorf("expected value %s, got %s, %v", "primary", value, err)
+	}
+	s, err = Open(primary, WithOpenTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("expected the writer to take the lock back, got %v", err)
+	}
+	if err := s.Put("test", []byte("key"), []byte("updated")); err != nil {
+		t.Error(err)
+	}
+	// locked again, reads fall back to the newest snapshot
+	if value, err := r.Get([]byte("test"), []byte("key")); err != nil || string(value) != "newest" {
+		t.Errorf("expected value %s, got %s, %v", "newest", value, err)
+	}
+	writeSnapshot("4.db", "latest", now.Add(time.Minute))
+	if err := r.refresh(); err != nil {
+		t.Error(err)
+	}
+	if r.Path() != filepath.Join(snapDir, "4.db") {
+		t.Errorf("expected newest snapshot, got %s", r.Path())
+	}
+}
+
+func TestReplicaSetOptions(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "replica_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	primary := filepath.Join(dir, "primary.db")
+	s, err := Open(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	opts := make([]Option, 1, 4)
+	opts[0] = WithMaxCacheSize(10)
+	r, err := OpenReplicaSet(primary, dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if extra := opts[1:cap(opts)]; extra[0] != nil || extra[1] != nil {
+		t.Error("expected the options of the caller to be left alone")
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("expected a second Close to do nothing, got %v", err)
+	}
+}
+
+func TestReplicaSetNoReplica(t *testing.T) {
+	dir, err := os.MkdirTemp(os.TempDir(), "replica_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := OpenReplicaSet(filepath.Join(dir, "missing.db"), dir); err != ErrNoReplica {
+		t.Errorf("expected error %s, got %s", ErrNoReplica, err)
+	}
+}
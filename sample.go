@@ -0,0 +1,33 @@
+package gostore
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Sample returns a random sample of the live records in a namespace, each
+// record being picked with the given probability. It reads the namespace in
+// a single pass.
+func (s *Store) Sample(namespace []byte, fraction float64) ([]KV, error) {
+	var kvs []KV
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+		now := time.Now()
+		return bucket.ForEach(func(k, v []byte) error {
+			if rand.Float64() >= fraction {
+				return nil
+			}
+			if value, ok := liveValue(v, now); ok {
+				kvs = append(kvs, KV{Key: bytes.Clone(k), Value: value})
+			}
+			return nil
+		})
+	})
+	return kvs, err
+}
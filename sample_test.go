@@ -0,0 +1,52 @@
+package gostore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 1000; i++ {
+		if err := s.Put("test", []byte(fmt.Sprintf("key%d", i)), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	all, err := s.Sample([]byte("test"), 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(all) != 1000 {
+		t.Errorf("expected %d records, got %d", 1000, len(all))
+	}
+	some, err := s.Sample([]byte("test"), 0.1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(some) < 30 || len(some) > 300 {
+		t.Errorf("expected about %d records, got %d", 100, len(some))
+	}
+	for _, kv := range some {
+		if string(kv.Value) != "value" {
+			t.Errorf("expected value %s, got %s", "value", kv.Value)
+		}
+	}
+	none, err := s.Sample([]byte("missing"), 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(none) != 0 {
+		t.Errorf("expected %d records, got %d", 0, len(none))
+	}
+}
@@ -0,0 +1,201 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _defaultChunkSize = 1000
+
+// Scan returns up to limit live records whose key starts with prefix, in key
+// order. A limit of zero or less returns all of them. With WithHashedKeys
+// the prefix must be empty; others fail with ErrHashedKeys.
+func (s *Store) Scan(namespace, prefix []byte, limit int) ([]KV, error) {
+	if len(prefix) > 0 && s.opt.Load().hashedKeys {
+		return nil, ErrHashedKeys
+	}
+	var kvs []KV
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+		now := time.Now()
+		c := bucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			value, ok := liveValue(v, now)
+			if !ok {
+				continue
+			}
+			kvs = append(kvs, KV{Key: bytes.Clone(k), Value: value})
+			if limit > 0 && len(kvs) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return kvs, err
+}
+
+// Range calls fn for every live record with a key in [start, end), in key
+// order. A nil end means no upper bound. Iteration stops at the first error
+// returned by fn, which Range returns. k is only valid until fn returns.
+// With WithHashedKeys both bounds must be nil; others fail with
+// ErrHashedKeys.
+func (s *Store) Range(namespace, start, end []byte, fn func(k, v []byte) error) error {
+	if (len(start) > 0 || end != nil) && s.opt.Load().hashedKeys {
+		return ErrHashedKeys
+	}
+	return s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+		now := time.Now()
+		c := bucket.Cursor()
+		for k, v := c.Seek(start); k != nil && (end == nil || bytes.Compare(k, end) < 0); k, v = c.Next() {
+			value, ok := liveValue(v, now)
+			if !ok {
+				continue
+			}
+			if err := fn(k, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Keys returns up to limit live keys of a namespace in key order, starting
+// at cursor, and the cursor of the next page, nil after the last one. A nil
+// cursor starts at the first key; a limit of zero or less returns 1000
+// keys. Cursors are stored keys, so they page through hashed keys too.
+func (s *Store) Keys(namespace []byte, cursor []byte, limit int) (keys [][]byte, nextCursor []byte, err error) {
+	if limit <= 0 {
+		limit = _defaultChunkSize
+	}
+	err = s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+		now := time.Now()
+		c := bucket.Cursor()
+		k, v := c.Seek(cursor)
+		for ; k != nil && len(keys) < limit; k, v = c.Next() {
+			if expire, ok := expireOf(v); ok && (expire.IsZero() || expire.After(now)) {
+				keys = append(keys, bytes.Clone(k))
+			}
+		}
+		if k != nil {
+			nextCursor = bytes.Clone(k)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, nextCursor, nil
+}
+
+// SnapshotIterate calls fn for every live record of a namespace in key
+// order, reading chunkSize records, 1000 by default, per short read
+// transaction. Each chunk is a consistent snapshot, but writes committed
+// between chunks are seen by the later ones. fn runs outside the
+// transactions, so it may be slow or write to the store. Iteration stops
+// at the first error returned by fn.
+func (s *Store) SnapshotIterate(namespace []byte, chunkSize int, fn func(k, v []byte) error) error {
+	return s.iterateChunks(namespace, nil, chunkSize, fn)
+}
+
+// StreamKeys sends the live records of a namespace whose key starts with
+// prefix to the returned channel, in key order, reading them in chunks as
+// SnapshotIterate does. Both channels are closed when iteration ends; the
+// error channel receives at most one error, ctx.Err() if ctx is done
+// first. With WithHashedKeys a non-empty prefix fails with ErrHashedKeys.
+func (s *Store) StreamKeys(ctx context.Context, namespace, prefix []byte) (<-chan KV, <-chan error) {
+	kvs := make(chan KV)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(kvs)
+		err := s.iterateChunks(namespace, prefix, _defaultChunkSize, func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			select {
+			case kvs <- KV{Key: k, Value: v}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return kvs, errc
+}
+
+// iterateChunks calls fn with copies of the live records whose key starts
+// with prefix, reading chunkSize of them per read transaction.
+func (s *Store) iterateChunks(namespace, prefix []byte, chunkSize int, fn func(k, v []byte) error) error {
+	if len(prefix) > 0 && s.opt.Load().hashedKeys {
+		return ErrHashedKeys
+	}
+	if chunkSize <= 0 {
+		chunkSize = _defaultChunkSize
+	}
+	resume := prefix
+	for {
+		var (
+			chunk []KV
+			next  []byte
+		)
+		if err := s.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(namespace)
+			if bucket == nil {
+				return nil
+			}
+			now := time.Now()
+			c := bucket.Cursor()
+			k, v := c.Seek(resume)
+			for ; k != nil && bytes.HasPrefix(k, prefix) && len(chunk) < chunkSize; k, v = c.Next() {
+				if value, ok := liveValue(v, now); ok {
+					chunk = append(chunk, KV{Key: bytes.Clone(k), Value: value})
+				}
+			}
+			if k != nil && bytes.HasPrefix(k, prefix) {
+				next = bytes.Clone(k)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, kv := range chunk {
+			if err := fn(kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		resume = next
+	}
+}
+
+// liveValue decodes a record, reporting false if it is expired or is not a
+// record at all.
+func liveValue(v []byte, now time.Time) ([]byte, bool) {
+	var value valueT
+	if err := value.UnmarshalBinary(v); err != nil {
+		return nil, false
+	}
+	if !value.Expire.IsZero() && now.After(value.Expire) {
+		return nil, false
+	}
+	return value.Value, true
+}
@@ -0,0 +1,237 @@
+package gostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		for _, attr := range []string{"email", "name"} {
+			key := fmt.Sprintf("user:%d:%s", i, attr)
+			if err := s.Put("test", []byte(key), []byte(attr)); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	if err := s.Put("test", []byte("users"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+
+	kvs, err := s.Scan([]byte("test"), []byte("user:1:"), 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 2 {
+		t.Fatalf("expected %d records, got %d", 2, len(kvs))
+	}
+	if string(kvs[0].Key) != "user:1:email" || string(kvs[0].Value) != "email" {
+		t.Errorf("unexpected record %s=%s", kvs[0].Key, kvs[0].Value)
+	}
+	if string(kvs[1].Key) != "user:1:name" || string(kvs[1].Value) != "name" {
+		t.Errorf("unexpected record %s=%s", kvs[1].Key, kvs[1].Value)
+	}
+
+	kvs, err = s.Scan([]byte("test"), []byte("user:"), 4)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 4 {
+		t.Errorf("expected %d records, got %d", 4, len(kvs))
+	}
+
+	kvs, err = s.Scan([]byte("missing"), nil, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 0 {
+		t.Errorf("expected %d records, got %d", 0, len(kvs))
+	}
+}
+
+func TestRange(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := s.Put("test", []byte(fmt.Sprintf("%04d", i)), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var keys []string
+	if err := s.Range([]byte("test"), []byte("0003"), []byte("0006"), func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+	if fmt.Sprint(keys) != "[0003 0004 0005]" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	keys = nil
+	if err := s.Range([]byte("test"), []byte("0008"), nil, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+	if fmt.Sprint(keys) != "[0008 0009]" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	errStop := errors.New("stop")
+	n := 0
+	if err := s.Range([]byte("test"), nil, nil, func(k, v []byte) error {
+		n++
+		return errStop
+	}); err != errStop {
+		t.Errorf("expected error %s, got %s", errStop, err)
+	}
+	if n != 1 {
+		t.Errorf("expected %d calls, got %d", 1, n)
+	}
+}
+
+func TestSnapshotIterate(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := s.Put("ns", []byte{byte('a' + i)}, []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	var keys []byte
+	if err := s.SnapshotIterate([]byte("ns"), 3, func(k, v []byte) error {
+		keys = append(keys, k...)
+		// writes between chunks don't block iteration
+		return s.Put("other", k, v)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if string(keys) != "abcdefghij" {
+		t.Errorf("expected abcdefghij got %s", keys)
+	}
+	if err := s.SnapshotIterate([]byte("missing"), 3, func(k, v []byte) error {
+		t.Error("unexpected record")
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStreamKeys(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"a1", "b1", "b2", "b3", "c1"} {
+		if err := s.Put("ns", []byte(k), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	kvs, errc := s.StreamKeys(context.Background(), []byte("ns"), []byte("b"))
+	var keys []string
+	for kv := range kvs {
+		keys = append(keys, string(kv.Key))
+	}
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+	if fmt.Sprint(keys) != "[b1 b2 b3]" {
+		t.Errorf("expected [b1 b2 b3] got %v", keys)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	kvs, errc = s.StreamKeys(ctx, []byte("ns"), nil)
+	<-kvs
+	cancel()
+	for range kvs {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 7; i++ {
+		if err := s.Put("ns", []byte{byte('a' + i)}, []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	var (
+		pages  []string
+		cursor []byte
+	)
+	for {
+		keys, next, err := s.Keys([]byte("ns"), cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := ""
+		for _, k := range keys {
+			page += string(k)
+		}
+		pages = append(pages, page)
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if fmt.Sprint(pages) != "[abc def g]" {
+		t.Errorf("expected [abc def g] got %v", pages)
+	}
+	if keys, next, err := s.Keys([]byte("missing"), nil, 3); err != nil || keys != nil || next != nil {
+		t.Errorf("expected no keys got %q, %q, %v", keys, next, err)
+	}
+}
@@ -0,0 +1,381 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/millken/gostore"
+)
+
+const (
+	_maxCursors       = 1024
+	_defaultScanCount = 10
+)
+
+// exec runs a command and writes its reply, reporting whether the client
+// asked to close the connection
+func (s *Server) exec(w writer, args [][]byte) (quit bool) {
+	name, args := strings.ToUpper(string(args[0])), args[1:]
+	keys := args
+	switch name {
+	case "DEL", "EXISTS":
+	case "GET", "SET", "SETEX", "EXPIRE", "TTL":
+		keys = args[:min(len(args), 1)]
+	default:
+		keys = nil
+	}
+	for _, key := range keys {
+		if ns, _ := s.split(key); gostore.IsInternalNamespace(string(ns)) {
+			w.error("ERR internal namespace " + strconv.Quote(string(ns)))
+			return false
+		}
+	}
+	switch name {
+	case "PING":
+		switch len(args) {
+		case 0:
+			w.simple("PONG")
+		case 1:
+			w.bulk(args[0])
+		default:
+			wrongArgs(w, name)
+		}
+	case "ECHO":
+		if len(args) != 1 {
+			wrongArgs(w, name)
+			return false
+		}
+		w.bulk(args[0])
+	case "QUIT":
+		w.simple("OK")
+		return true
+	case "COMMAND":
+		// redis-cli asks for the command docs on connect
+		w.array(0)
+	case "GET":
+		if len(args) != 1 {
+			wrongArgs(w, name)
+			return false
+		}
+		s.get(w, args[0])
+	case "SET":
+		if len(args) < 2 {
+			wrongArgs(w, name)
+			return false
+		}
+		s.set(w, args[0], args[1], args[2:])
+	case "SETEX":
+		if len(args) != 3 {
+			wrongArgs(w, name)
+			return false
+		}
+		ttl, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil || ttl <= 0 {
+			w.error("ERR invalid expire time in 'setex' command")
+			return false
+		}
+		s.put(w, args[0], args[2], ttl, false)
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			wrongArgs(w, name)
+			return false
+		}
+		s.count(w, args, name == "DEL")
+	case "EXPIRE":
+		if len(args) != 2 {
+			wrongArgs(w, name)
+			return false
+		}
+		ttl, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			w.error("ERR value is not an integer or out of range")
+			return false
+		}
+		s.expire(w, args[0], ttl)
+	case "TTL":
+		if len(args) != 1 {
+			wrongArgs(w, name)
+			return false
+		}
+		s.ttl(w, args[0])
+	case "SCAN":
+		if len(args) == 0 {
+			wrongArgs(w, name)
+			return false
+		}
+		s.scan(w, args)
+	default:
+		w.error("ERR unknown command '" + name + "'")
+	}
+	return false
+}
+
+func wrongArgs(w writer, name string) {
+	w.error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+}
+
+// missing reports whether err means the key does not exist
+func missing(err error) bool {
+	return errors.Is(err, gostore.ErrKeyNotFound) || errors.Is(err, gostore.ErrKeyExpired)
+}
+
+// split returns the namespace and store key of a key
+func (s *Server) split(key []byte) ([]byte, []byte) {
+	if i := bytes.Index(key, []byte(s.opt.separator)); i >= 0 {
+		return key[:i], key[i+len(s.opt.separator):]
+	}
+	return []byte(s.opt.namespace), key
+}
+
+// join returns the key of a namespace and store key
+func (s *Server) join(namespace string, key []byte) []byte {
+	if namespace == s.opt.namespace {
+		return key
+	}
+	return append([]byte(namespace+s.opt.separator), key...)
+}
+
+func (s *Server) get(w writer, key []byte) {
+	ns, k := s.split(key)
+	v, err := s.store.Get(ns, k)
+	switch {
+	case missing(err):
+		w.null()
+	case err != nil:
+		w.error("ERR " + err.Error())
+	default:
+		w.bulk(v)
+	}
+}
+
+func (s *Server) set(w writer, key, value []byte, opts [][]byte) {
+	var (
+		ttl int64
+		nx  bool
+	)
+	for i := 0; i < len(opts); i++ {
+		switch strings.ToUpper(string(opts[i])) {
+		case "NX":
+			nx = true
+		case "EX", "PX":
+			if i+1 == len(opts) {
+				w.error("ERR syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(string(opts[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				w.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			if strings.EqualFold(string(opts[i]), "PX") {
+				// the store keeps expiries in seconds
+				n = (n + 999) / 1000
+			}
+			ttl = n
+			i++
+		default:
+			w.error("ERR syntax error")
+			return
+		}
+	}
+	s.put(w, key, value, ttl, nx)
+}
+
+func (s *Server) put(w writer, key, value []byte, ttl int64, nx bool) {
+	ns, k := s.split(key)
+	if nx {
+		stored, err := s.store.PutNX(ns, k, value, ttl)
+		switch {
+		case err != nil:
+			w.error("ERR " + err.Error())
+		case stored:
+			w.simple("OK")
+		default:
+			w.null()
+		}
+		return
+	}
+	if err := s.store.PutWithTTL(ns, k, value, ttl); err != nil {
+		w.error("ERR " + err.Error())
+		return
+	}
+	w.simple("OK")
+}
+
+// count replies with the number of keys that exist, deleting them if del
+func (s *Server) count(w writer, keys [][]byte, del bool) {
+	var n int64
+	for _, key := range keys {
+		ns, k := s.split(key)
+		_, err := s.store.Get(ns, k)
+		if missing(err) {
+			continue
+		}
+		if err != nil {
+			w.error("ERR " + err.Error())
+			return
+		}
+		if del {
+			if err := s.store.Delete(string(ns), k); err != nil {
+				w.error("ERR " + err.Error())
+				return
+			}
+		}
+		n++
+	}
+	w.integer(n)
+}
+
+func (s *Server) expire(w writer, key []byte, ttl int64) {
+	if ttl <= 0 {
+		s.count(w, [][]byte{key}, true)
+		return
+	}
+	ns, k := s.split(key)
+	err := s.store.Touch(ns, k, ttl)
+	switch {
+	case missing(err):
+		w.integer(0)
+	case err != nil:
+		w.error("ERR " + err.Error())
+	default:
+		w.integer(1)
+	}
+}
+
+func (s *Server) ttl(w writer, key []byte) {
+	ns, k := s.split(key)
+	_, remaining, err := s.store.GetTTL(ns, k)
+	switch {
+	case missing(err):
+		w.integer(-2)
+	case err != nil:
+		w.error("ERR " + err.Error())
+	case remaining == 0:
+		w.integer(-1)
+	default:
+		w.integer(int64((remaining + 999999999) / 1e9))
+	}
+}
+
+// scanPos is where a SCAN continues: the next key of a namespace
+type scanPos struct {
+	namespace string
+	key       []byte
+}
+
+// cursors maps SCAN cursors to positions, keeping the newest ones
+type cursors struct {
+	mu   sync.Mutex
+	last uint64
+	pos  map[uint64]scanPos
+}
+
+func (c *cursors) add(pos scanPos) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pos == nil {
+		c.pos = make(map[uint64]scanPos)
+	}
+	c.last++
+	c.pos[c.last] = pos
+	delete(c.pos, c.last-_maxCursors)
+	return c.last
+}
+
+func (c *cursors) take(id uint64) (scanPos, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pos, ok := c.pos[id]
+	delete(c.pos, id)
+	return pos, ok
+}
+
+func (s *Server) scan(w writer, args [][]byte) {
+	id, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		w.error("ERR invalid cursor")
+		return
+	}
+	var pos scanPos
+	if id != 0 {
+		var ok bool
+		if pos, ok = s.cursors.take(id); !ok {
+			w.error("ERR invalid cursor")
+			return
+		}
+	}
+	match, count := "", _defaultScanCount
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			w.error("ERR syntax error")
+			return
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			match = string(args[i+1])
+		case "COUNT":
+			if count, err = strconv.Atoi(string(args[i+1])); err != nil || count <= 0 {
+				w.error("ERR syntax error")
+				return
+			}
+		default:
+			w.error("ERR syntax error")
+			return
+		}
+	}
+
+	namespaces, err := s.store.Namespaces()
+	if err != nil {
+		w.error("ERR " + err.Error())
+		return
+	}
+	var (
+		keys [][]byte
+		next uint64
+		seen int
+	)
+	for _, ns := range namespaces {
+		if ns < pos.namespace {
+			continue
+		}
+		var cursor []byte
+		if ns == pos.namespace {
+			cursor = pos.key
+		}
+		page, nextKey, err := s.store.Keys([]byte(ns), cursor, count-seen)
+		if err != nil {
+			w.error("ERR " + err.Error())
+			return
+		}
+		seen += len(page)
+		for _, k := range page {
+			if ns == s.opt.namespace && bytes.Contains(k, []byte(s.opt.separator)) {
+				// GET would look this key up in another namespace
+				continue
+			}
+			key := s.join(ns, k)
+			if match == "" || matchGlob(match, string(key)) {
+				keys = append(keys, key)
+			}
+		}
+		if nextKey != nil {
+			next = s.cursors.add(scanPos{namespace: ns, key: nextKey})
+			break
+		}
+		if seen >= count {
+			// continue at the start of the next namespace
+			next = s.cursors.add(scanPos{namespace: ns + "\x00"})
+			break
+		}
+	}
+	w.array(2)
+	w.bulk([]byte(strconv.FormatUint(next, 10)))
+	w.array(len(keys))
+	for _, k := range keys {
+		w.bulk(k)
+	}
+}
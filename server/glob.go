@@ -0,0 +1,76 @@
+package server
+
+// matchGlob reports whether s matches the Redis glob pattern: * matches any
+// bytes, ? any single byte, [abc], [a-z] and [^abc] a byte of a class, and
+// \ escapes the next byte. Unlike path.Match, * and ? also match "/".
+func matchGlob(pattern, s string) bool {
+	// on a mismatch, retry after the last * with it matching one more byte
+	px, sx := 0, 0
+	starPx, starSx := 0, 0
+	for px < len(pattern) || sx < len(s) {
+		if px < len(pattern) {
+			if pattern[px] == '*' {
+				starPx, starSx = px, sx+1
+				px++
+				continue
+			}
+			if sx < len(s) {
+				if n, ok := matchByte(pattern[px:], s[sx]); ok {
+					px += n
+					sx++
+					continue
+				}
+			}
+		}
+		if starSx > 0 && starSx <= len(s) {
+			px, sx = starPx, starSx
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// matchByte reports whether c matches the element at the start of pattern,
+// which is not a *, and the length of that element
+func matchByte(pattern string, c byte) (int, bool) {
+	switch pattern[0] {
+	case '?':
+		return 1, true
+	case '\\':
+		if len(pattern) == 1 {
+			return 1, c == '\\'
+		}
+		return 2, c == pattern[1]
+	case '[':
+	default:
+		return 1, c == pattern[0]
+	}
+	i, not, matched := 1, false, false
+	if i < len(pattern) && pattern[i] == '^' {
+		not = true
+		i++
+	}
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			matched = matched || c == pattern[i+1]
+			i += 2
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || lo <= c && c <= hi
+			i += 3
+		default:
+			matched = matched || c == pattern[i]
+			i++
+		}
+	}
+	if i < len(pattern) {
+		// the closing ]
+		i++
+	}
+	return i, matched != not
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	_maxBulkLen      = 512 << 20
+	_maxMultiBulkLen = 1 << 20
+)
+
+var errProtocol = errors.New("protocol error")
+
+// readCommand reads a RESP array of bulk strings, or an inline command,
+// failing with errProtocol if its arguments total more than limit bytes
+func readCommand(r *bufio.Reader, limit int) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return bytes.Fields(line), nil
+	}
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > _maxMultiBulkLen {
+		return nil, errProtocol
+	}
+	var (
+		args  [][]byte
+		total int
+	)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > _maxBulkLen || total+size > limit {
+			return nil, errProtocol
+		}
+		total += size
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+		if !bytes.HasSuffix(arg, []byte("\r\n")) {
+			return nil, errProtocol
+		}
+		args = append(args, arg[:size])
+	}
+	return args, nil
+}
+
+// readLine reads a line without its CRLF
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errProtocol
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// writer encodes RESP replies
+type writer struct {
+	*bufio.Writer
+}
+
+func (w writer) simple(s string) {
+	w.WriteByte('+')
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// _lineBreaks replaces the line breaks an error reply cannot hold, which
+// would end it early and start another reply
+var _lineBreaks = strings.NewReplacer("\r", " ", "\n", " ")
+
+func (w writer) error(s string) {
+	w.WriteByte('-')
+	w.WriteString(_lineBreaks.Replace(s))
+	w.WriteString("\r\n")
+}
+
+func (w writer) integer(n int64) {
+	w.WriteByte(':')
+	w.WriteString(strconv.FormatInt(n, 10))
+	w.WriteString("\r\n")
+}
+
+func (w writer) null() {
+	w.WriteString("$-1\r\n")
+}
+
+func (w writer) bulk(b []byte) {
+	w.WriteByte('$')
+	w.WriteString(strconv.Itoa(len(b)))
+	w.WriteString("\r\n")
+	w.Write(b)
+	w.WriteString("\r\n")
+}
+
+func (w writer) array(n int) {
+	w.WriteByte('*')
+	w.WriteString(strconv.Itoa(n))
+	w.WriteString("\r\n")
+}
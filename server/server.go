@@ -0,0 +1,269 @@
+// Package server serves a gostore.Store over the Redis protocol (RESP), so
+// redis-cli and Redis clients in any language can read and write it.
+//
+// A key is mapped to a namespace and a key of the store by its prefix up
+// to the first separator, ":" by default: "users:42" is key "42" of
+// namespace "users". Keys without a separator are in the default
+// namespace, so keys of the default namespace stored with a separator
+// through the Go API cannot be reached and SCAN leaves them out. The
+// commands are PING, ECHO, QUIT, GET, SET with EX, PX and NX, SETEX, DEL,
+// EXISTS, EXPIRE, TTL and SCAN with MATCH, a Redis glob, and COUNT.
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/millken/gostore"
+)
+
+const (
+	_defaultNamespace      = "default"
+	_defaultMaxCommandSize = 64 << 20
+)
+
+// ErrServerClosed is returned by Serve after Shutdown or Close
+var ErrServerClosed = errors.New("server closed")
+
+// Option configures a Server
+type Option func(*option) error
+
+type option struct {
+	separator      string
+	namespace      string
+	maxCommandSize int
+	logger         *slog.Logger
+}
+
+// WithSeparator sets the separator between the namespace and the key
+func WithSeparator(sep string) Option {
+	return func(o *option) error {
+		if sep == "" {
+			return errors.New("empty separator")
+		}
+		o.separator = sep
+		return nil
+	}
+}
+
+// WithDefaultNamespace sets the namespace of the keys without a separator,
+// the default namespace of the store by default
+func WithDefaultNamespace(namespace string) Option {
+	return func(o *option) error {
+		o.namespace = namespace
+		return nil
+	}
+}
+
+// WithMaxCommandSize limits the total bytes of the arguments of a command,
+// 64 MiB by default. Larger commands get a protocol error and the
+// connection is closed.
+func WithMaxCommandSize(n int) Option {
+	return func(o *option) error {
+		if n <= 0 {
+			return errors.New("command size must be positive")
+		}
+		o.maxCommandSize = n
+		return nil
+	}
+}
+
+// WithLogger logs the panics of command handlers to l, slog.Default() by
+// default
+func WithLogger(l *slog.Logger) Option {
+	return func(o *option) error {
+		o.logger = l
+		return nil
+	}
+}
+
+// Server serves a store over RESP
+type Server struct {
+	store *gostore.Store
+	opt   option
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+	cursors   cursors
+}
+
+// New returns a server of store
+func New(store *gostore.Store, opts ...Option) (*Server, error) {
+	opt := option{separator: ":", namespace: _defaultNamespace, maxCommandSize: _defaultMaxCommandSize, logger: slog.Default()}
+	for _, o := range opts {
+		if err := o(&opt); err != nil {
+			return nil, err
+		}
+	}
+	return &Server{
+		store:     store,
+		opt:       opt,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// ListenAndServe listens on the TCP address addr and serves it
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Shutdown or Close, when it returns
+// ErrServerClosed. l is closed when Serve returns.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections, lets every connection finish the
+// command it is running and closes it. If ctx is done first, the remaining
+// connections are closed at once and ctx.Err() is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	// wake up the connections waiting for a command
+	for c := range s.conns {
+		_ = c.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.closeConns()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Close closes the listeners and all connections at once
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	s.mu.Unlock()
+	s.closeConns()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// track registers a new connection, reporting false once the server is
+// closed
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		// a bad command must not take the process down
+		if p := recover(); p != nil {
+			s.opt.logger.Error("server: command panicked", "remote", conn.RemoteAddr().String(), "panic", fmt.Sprint(p))
+		}
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+	r := bufio.NewReader(conn)
+	w := writer{bufio.NewWriter(conn)}
+	for {
+		args, err := readCommand(r, s.opt.maxCommandSize)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				w.error("ERR Protocol error")
+				w.Flush()
+			}
+			return
+		}
+		quit := false
+		if len(args) > 0 {
+			quit = s.exec(w, args)
+		}
+		// replies to pipelined commands are sent together
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit || s.isClosed() {
+			return
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/millken/gostore"
+)
+
+// client sends commands and returns the replies in RESP
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) do(t *testing.T, args ...string) string {
+	t.Helper()
+	fmt.Fprintf(c.conn, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(c.conn, "$%d\r\n%s\r\n", len(a), a)
+	}
+	reply, err := c.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+// read reads a reply, with arrays flattened into one line
+func (c *client) read() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch line[0] {
+	case '$':
+		if line == "$-1" {
+			return "(nil)", nil
+		}
+		v, err := c.r.ReadString('\n')
+		return strings.TrimRight(v, "\r\n"), err
+	case '*':
+		var n int
+		fmt.Sscan(line[1:], &n)
+		items := make([]string, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(items, " ") + "]", nil
+	}
+	return line, nil
+}
+
+func TestServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	store, err := gostore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	srv, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &client{conn: conn, r: bufio.NewReader(conn)}
+
+	for _, step := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"PING"}, "+PONG"},
+		{[]string{"SET", "users:ann", "1"}, "+OK"},
+		{[]string{"GET", "users:ann"}, "1"},
+		{[]string{"SET", "users:ann", "2", "NX"}, "(nil)"},
+		{[]string{"SETEX", "session", "60", "token"}, "+OK"},
+		{[]string{"TTL", "session"}, ":60"},
+		{[]string{"TTL", "users:ann"}, ":-1"},
+		{[]string{"TTL", "users:bob"}, ":-2"},
+		{[]string{"EXPIRE", "users:ann", "100"}, ":1"},
+		{[]string{"EXPIRE", "users:bob", "100"}, ":0"},
+		{[]string{"EXISTS", "users:ann", "users:bob"}, ":1"},
+		{[]string{"SET", "users:bob", "3", "PX", "1500"}, "+OK"},
+		{[]string{"TTL", "users:bob"}, ":2"},
+		{[]string{"SCAN", "0", "MATCH", "users:*", "COUNT", "100"}, "[0 [users:ann users:bob]]"},
+		{[]string{"DEL", "users:ann", "users:carol"}, ":1"},
+		{[]string{"GET", "users:ann"}, "(nil)"},
+		{[]string{"NOPE"}, "-ERR unknown command 'NOPE'"},
+		{[]string{"SET", "\x00changes:1", "x"}, `-ERR internal namespace "\x00changes"`},
+		{[]string{"DEL", "users:bob", "\x00offsets:x"}, `-ERR internal namespace "\x00offsets"`},
+		{[]string{"SET", "__changes:1", "x"}, "+OK"},
+	} {
+		if got := c.do(t, step.args...); got != step.want {
+			t.Errorf("%v: expected %s got %s", step.args, step.want, got)
+		}
+	}
+	if v, err := store.Get([]byte("default"), []byte("session")); err != nil || string(v) != "token" {
+		t.Errorf("expected token got %s, %v", v, err)
+	}
+
+	// a SCAN goes over all namespaces page by page, leaving out default
+	// namespace keys with a separator
+	for i := 0; i < 5; i++ {
+		c.do(t, "SET", fmt.Sprintf("ns%d:k", i), "v")
+	}
+	if err := store.Put("default", []byte("ns0:k"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	cursor, keys := "0", 0
+	for {
+		reply := c.do(t, "SCAN", cursor, "COUNT", "2")
+		fields := strings.Fields(strings.Trim(reply, "[]"))
+		cursor = fields[0]
+		keys += len(fields) - 1
+		if cursor == "0" {
+			break
+		}
+	}
+	if keys != 8 {
+		t.Errorf("expected 8 keys got %d", keys)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Error(err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("expected error %s, got %v", ErrServerClosed, err)
+	}
+	if _, err := c.read(); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+func TestServerProtocolError(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	srv, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+
+	for _, req := range []string{"*4611686018427387904\r\n", "*2\r\n+GET\r\n"} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &client{conn: conn, r: bufio.NewReader(conn)}
+		fmt.Fprint(conn, req)
+		if reply, err := c.read(); err != nil || reply != "-ERR Protocol error" {
+			t.Errorf("expected a protocol error got %s, %v", reply, err)
+		}
+		conn.Close()
+	}
+	// the server still serves
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &client{conn: conn, r: bufio.NewReader(conn)}
+	if got := c.do(t, "PING"); got != "+PONG" {
+		t.Errorf("expected +PONG got %s", got)
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	store, err := gostore.Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	srv, err := New(store, WithMaxCommandSize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &client{conn: conn, r: bufio.NewReader(conn)}
+	// line breaks in an error reply do not start another reply
+	if got := c.do(t, "NO\r\n+OK"); got != "-ERR unknown command 'NO  +OK'" {
+		t.Errorf("expected an unknown command error got %q", got)
+	}
+	if got := c.do(t, "PING"); got != "+PONG" {
+		t.Errorf("expected +PONG got %s", got)
+	}
+	// the arguments total more than 16 bytes
+	if got := c.do(t, "SET", "key", "0123456789abcdef"); got != "-ERR Protocol error" {
+		t.Errorf("expected a protocol error got %s", got)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"users:*", "users:a/b", true},
+		{"users:*", "users:", true},
+		{"users:*", "user", false},
+		{"*", "", true},
+		{"a?c", "a/c", true},
+		{"a?c", "ac", false},
+		{"*b*d", "abcabd", true},
+		{"*b*d", "abcabe", false},
+		{"[a-c]x", "bx", true},
+		{"[c-a]x", "bx", true},
+		{"[^a-c]x", "bx", false},
+		{"[^a-c]x", "dx", true},
+		{"[ab\\]]", "]", true},
+		{"h\\*o", "h*o", true},
+		{"h\\*o", "hello", false},
+		{"a[", "a", false},
+	} {
+		if got := matchGlob(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("%q %q: expected %v got %v", tt.pattern, tt.s, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package gostore
+
+import (
+	"bytes"
+	"math/rand/v2"
+)
+
+// _maxShadowReads bounds the shadow reads in flight; reads past it are not
+// mirrored.
+const _maxShadowReads = 64
+
+// ShadowMismatch is a read whose result differed between the store and its
+// shadow.
+type ShadowMismatch struct {
+	Namespace []byte
+	Key       []byte
+	Value     []byte
+	Err       error
+	// ShadowValue and ShadowErr are what the shadow store returned.
+	ShadowValue []byte
+	ShadowErr   error
+}
+
+type shadowConfig struct {
+	store      KVStore
+	rate       float64
+	onMismatch func(ShadowMismatch)
+}
+
+// WithShadowStore mirrors a sampleRate fraction of Get calls to other in the
+// background and compares the results, to validate a migration to another
+// engine. Missing and expired keys are considered equal. Mismatches are
+// counted, see ShadowStats, and passed to onMismatch if it is not nil.
+func WithShadowStore(other KVStore, sampleRate float64, onMismatch func(ShadowMismatch)) Option {
+	return func(o *option) error {
+		o.shadow = &shadowConfig{store: other, rate: sampleRate, onMismatch: onMismatch}
+		return nil
+	}
+}
+
+// ShadowStats returns the number of reads mirrored to the shadow store and
+// how many of them did not match.
+func (s *Store) ShadowStats() (reads, mismatches uint64) {
+	return s.shadowReads.Load(), s.shadowMismatches.Load()
+}
+
+// shadowGet mirrors a read of the store, whose result was value and err
+func (s *Store) shadowGet(namespace, key, value []byte, err error) {
+	cfg := s.opt.Load().shadow
+	if cfg == nil || rand.Float64() >= cfg.rate {
+		return
+	}
+	select {
+	case s.shadowSem <- struct{}{}:
+	default:
+		return
+	}
+	namespace, key, value = bytes.Clone(namespace), bytes.Clone(key), bytes.Clone(value)
+	go func() {
+		defer func() { <-s.shadowSem }()
+		got, gotErr := cfg.store.Get(namespace, key)
+		s.shadowReads.Add(1)
+		if shadowMatch(value, err, got, gotErr) {
+			return
+		}
+		s.shadowMismatches.Add(1)
+		if cfg.onMismatch != nil {
+			cfg.onMismatch(ShadowMismatch{
+				Namespace: namespace, Key: key, Value: value, Err: err,
+				ShadowValue: got, ShadowErr: gotErr,
+			})
+		}
+	}()
+}
+
+func shadowMatch(value []byte, err error, got []byte, gotErr error) bool {
+	code, gotCode := Code(err), Code(gotErr)
+	if code == CodeExpired {
+		code = CodeNotFound
+	}
+	if gotCode == CodeExpired {
+		gotCode = CodeNotFound
+	}
+	if code != CodeOK || gotCode != CodeOK {
+		return code == gotCode
+	}
+	return bytes.Equal(value, got)
+}
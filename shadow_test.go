@@ -0,0 +1,80 @@
+package gostore
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+// mapStore is a KVStore kept in memory.
+type mapStore struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{m: make(map[string]string)}
+}
+
+func (m *mapStore) Put(namespace string, key, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[namespace+"/"+string(key)] = string(value)
+	return nil
+}
+
+func (m *mapStore) Get(namespace, key []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.m[string(namespace)+"/"+string(key)]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return []byte(v), nil
+}
+
+func (m *mapStore) Delete(namespace string, key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, namespace+"/"+string(key))
+	return nil
+}
+
+func TestShadowStore(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	shadow := newMapStore()
+	mismatches := make(chan ShadowMismatch, 10)
+	s, err := Open(path, WithShadowStore(shadow, 1, func(m ShadowMismatch) { mismatches <- m }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("ns", []byte("same"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	_ = shadow.Put("ns", []byte("same"), []byte("v"))
+	if err := s.Put("ns", []byte("differs"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	_ = shadow.Put("ns", []byte("differs"), []byte("other"))
+
+	for _, k := range []string{"same", "missing", "differs"} {
+		_, _ = s.Get([]byte("ns"), []byte(k))
+	}
+	m := <-mismatches
+	if string(m.Key) != "differs" || string(m.Value) != "v" || string(m.ShadowValue) != "other" {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+	waitFor(t, func() bool {
+		reads, _ := s.ShadowStats()
+		return reads == 3
+	})
+	if _, mismatched := s.ShadowStats(); mismatched != 1 {
+		t.Errorf("expected 1 mismatch, got %d", mismatched)
+	}
+}
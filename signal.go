@@ -0,0 +1,41 @@
+package gostore
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// raise delivers sig to the process again once its handler is removed.
+var raise = func(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		_ = p.Signal(sig)
+	}
+}
+
+// HandleSignals closes the store when the process receives one of sigs,
+// SIGINT and SIGTERM by default, so writes are synced to disk before it
+// exits. The signal is then delivered again with its default behavior. An
+// application handling the signals itself should call Close from its own
+// handler instead. The returned function removes the handler.
+func (s *Store) HandleSignals(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	stopped := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case sig := <-ch:
+			_ = s.Close()
+			signal.Stop(ch)
+			raise(sig)
+		case <-stopped:
+			signal.Stop(ch)
+		}
+	}()
+	return func() { once.Do(func() { close(stopped) }) }
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package gostore
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleSignals(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	raised := make(chan os.Signal, 1)
+	defer func(f func(os.Signal)) { raise = f }(raise)
+	raise = func(sig os.Signal) { raised <- sig }
+
+	stop := s.HandleSignals(syscall.SIGUSR1)
+	defer stop()
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case sig := <-raised:
+		if sig != syscall.SIGUSR1 {
+			t.Errorf("expected %v got %v", syscall.SIGUSR1, sig)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal not handled")
+	}
+	if err := s.Put("test", []byte("key"), []byte("value")); err == nil {
+		t.Error("expected closed store to fail")
+	}
+
+	s2, err := Open(path, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if v, err := s2.Get([]byte("test"), []byte("key")); err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+}
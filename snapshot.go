@@ -0,0 +1,89 @@
+package gostore
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Snapshot writes a compacted copy of the store to a new bolt file at
+// dstPath, leaving out expired records. Unlike Backup, the copy only takes
+// the space of the live data. The copy is written to a temporary file next
+// to dstPath, named after the database file. CleanTemp only removes those
+// left in the directory of the database file; an interrupted snapshot to
+// another directory leaves its temporary file behind.
+func (s *Store) Snapshot(dstPath string) error {
+	f, err := os.CreateTemp(filepath.Dir(dstPath), filepath.Base(s.path)+".snapshot-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+	defer os.Remove(tmp)
+
+	dst, err := bolt.Open(tmp, _fileMode, &bolt.Options{NoSync: true})
+	if err != nil {
+		return err
+	}
+	err = s.view(func(tx *bolt.Tx) error {
+		return s.copyLive(tx, dst)
+	})
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, dstPath)
+}
+
+// copyLive copies the buckets of tx to dst, leaving out expired records.
+// Each destination transaction stays below the maximum transaction size.
+func (s *Store) copyLive(tx *bolt.Tx, dst *bolt.DB) error {
+	now := time.Now()
+	limit := s.maxTxSize()
+	return tx.ForEach(func(name []byte, src *bolt.Bucket) error {
+		collectable := gcCollectable(name)
+		if err := dst.Update(func(dtx *bolt.Tx) error {
+			bucket, err := dtx.CreateBucket(name)
+			if err != nil {
+				return err
+			}
+			return bucket.SetSequence(src.Sequence())
+		}); err != nil {
+			return err
+		}
+		c := src.Cursor()
+		k, v := c.First()
+		for k != nil {
+			if err := dst.Update(func(dtx *bolt.Tx) error {
+				bucket := dtx.Bucket(name)
+				// keys arrive in order, pack the pages
+				bucket.FillPercent = 1
+				for size := 0; k != nil && size < limit; k, v = c.Next() {
+					if v == nil {
+						continue
+					}
+					if collectable {
+						if expire, ok := expireOf(v); ok && !expire.IsZero() && now.After(expire) {
+							continue
+						}
+					}
+					if err := bucket.Put(k, v); err != nil {
+						return err
+					}
+					size += len(k) + len(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,54 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxTxSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		if err := s.Put("ns", []byte(k), []byte("value")); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.PutWithTTL([]byte("ns"), []byte("expired"), []byte("value"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+
+	dst, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(dst)
+	if err := s.Snapshot(dst); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	kvs, err := s2.Scan([]byte("ns"), nil, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 5 {
+		t.Errorf("expected 5 records got %d", len(kvs))
+	}
+	if _, err := s2.Get([]byte("ns"), []byte("expired")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
@@ -0,0 +1,153 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrBadPartitions is returned by SplitNamespace for less than two
+// partitions or a namespace that cannot be split.
+var ErrBadPartitions = errors.New("bad partitions")
+
+// PartitionFunc returns the partition of a stored key, in [0, partitions)
+type PartitionFunc func(key []byte, partitions int) int
+
+// HashPartition spreads keys evenly over the partitions by their FNV-1a
+// hash
+func HashPartition(key []byte, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// PartitionName returns the namespace holding partition i of namespace
+func PartitionName(namespace string, i int) string {
+	return fmt.Sprintf("%s#%d", namespace, i)
+}
+
+// Partitioned reads and writes a namespace split by SplitNamespace,
+// routing every key to its partition
+type Partitioned struct {
+	s          *Store
+	namespace  string
+	partitions int
+	fn         PartitionFunc
+}
+
+// Partitioned returns the accessor of a namespace split into partitions
+// by fn, nil for HashPartition. Until the split has finished, keys not yet
+// moved are read from the namespace itself.
+func (s *Store) Partitioned(namespace string, partitions int, fn PartitionFunc) *Partitioned {
+	if fn == nil {
+		fn = HashPartition
+	}
+	return &Partitioned{s: s, namespace: namespace, partitions: partitions, fn: fn}
+}
+
+// SplitNamespace moves the records of namespace into partitions
+// namespaces named by PartitionName, choosing the partition of each key
+// with fn, nil for HashPartition, and returns the accessor routing keys
+// the same way. Records are moved with their expiry, 1000 per write
+// transaction, bypassing views, watchers and the change log; the source
+// namespace is deleted at the end. Keep using the accessor while the split
+// runs: records it wrote to a partition are not overwritten by the move.
+// An immutable namespace cannot be split and fails with ErrImmutable.
+func (s *Store) SplitNamespace(namespace string, partitions int, fn PartitionFunc) (*Partitioned, error) {
+	if partitions < 2 || isInternal(namespace) || s.isView(namespace) || s.hasViews(namespace) {
+		return nil, fmt.Errorf("%w: %d of %s", ErrBadPartitions, partitions, namespace)
+	}
+	p := s.Partitioned(namespace, partitions, fn)
+	for done := false; !done; {
+		if err := s.update(func(tx *bolt.Tx) (err error) {
+			done, err = p.moveChunk(tx)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("failed to split namespace %s: %w", namespace, err)
+		}
+	}
+	return p, nil
+}
+
+// moveChunk moves up to 1000 records to their partitions, reporting
+// whether the source namespace is gone
+func (p *Partitioned) moveChunk(tx *bolt.Tx) (bool, error) {
+	src := tx.Bucket([]byte(p.namespace))
+	if src == nil {
+		return true, nil
+	}
+	if p.s.isFrozen([]byte(p.namespace)) {
+		return false, ErrFrozen
+	}
+	if p.s.isImmutable([]byte(p.namespace)) {
+		return false, ErrImmutable
+	}
+	c := src.Cursor()
+	n := 0
+	for k, v := c.First(); k != nil && n < _defaultChunkSize; k, v = c.First() {
+		dst, err := tx.CreateBucketIfNotExists([]byte(p.partition(k)))
+		if err != nil {
+			return false, err
+		}
+		if dst.Get(k) == nil {
+			if err := dst.Put(k, v); err != nil {
+				return false, err
+			}
+		}
+		p.s.invalidate(tx, []byte(p.namespace), k)
+		if err := c.Delete(); err != nil {
+			return false, err
+		}
+		n++
+	}
+	if n < _defaultChunkSize {
+		if q := p.s.quotas[p.namespace]; q != nil {
+			tx.OnCommit(q.reset)
+		}
+		return true, tx.DeleteBucket([]byte(p.namespace))
+	}
+	return false, nil
+}
+
+// partition returns the namespace of a stored key
+func (p *Partitioned) partition(key []byte) string {
+	return PartitionName(p.namespace, p.fn(key, p.partitions))
+}
+
+// Partition returns the namespace holding key
+func (p *Partitioned) Partition(key []byte) string {
+	return p.partition(p.s.diskKey(key))
+}
+
+// Get fetches the value of key from its partition
+func (p *Partitioned) Get(key []byte) ([]byte, error) {
+	v, err := p.s.Get([]byte(p.Partition(key)), key)
+	if err == ErrKeyNotFound {
+		return p.s.Get([]byte(p.namespace), key)
+	}
+	return v, err
+}
+
+// Put inserts a <key, value> record into its partition
+func (p *Partitioned) Put(key, value []byte) error {
+	return p.PutWithTTL(key, value, 0)
+}
+
+// PutWithTTL inserts a <key, value> record with TTL into its partition
+func (p *Partitioned) PutWithTTL(key, value []byte, ttl int64) error {
+	return p.s.PutWithTTL([]byte(p.Partition(key)), key, value, ttl)
+}
+
+// Delete deletes key from its partition, and from the namespace if it has
+// not been moved yet
+func (p *Partitioned) Delete(key []byte) error {
+	dk, partition := p.s.diskKey(key), p.Partition(key)
+	return p.s.update(func(tx *bolt.Tx) error {
+		if err := p.s.delete(tx, []byte(partition), dk); err != nil {
+			return err
+		}
+		return p.s.delete(tx, []byte(p.namespace), dk)
+	})
+}
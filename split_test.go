@@ -0,0 +1,95 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSplitNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const n = 2500
+	for i := 0; i < n; i++ {
+		if err := s.Put("big", []byte(fmt.Sprint(i)), []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.PutWithTTL([]byte("big"), []byte("ttl"), []byte("v"), 3600); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.SplitNamespace("big", 1, nil); !errors.Is(err, ErrBadPartitions) {
+		t.Errorf("expected error %s, got %v", ErrBadPartitions, err)
+	}
+	p, err := s.SplitNamespace("big", 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := s.Namespaces()
+	if err != nil || len(names) != 4 {
+		t.Errorf("expected 4 partitions got %v, %v", names, err)
+	}
+	total := 0
+	for i := 0; i < 4; i++ {
+		c, err := s.Count([]byte(PartitionName("big", i)))
+		if err != nil || c == 0 {
+			t.Errorf("expected records in partition %d got %d, %v", i, c, err)
+		}
+		total += c
+	}
+	if total != n+1 {
+		t.Errorf("expected %d records got %d", n+1, total)
+	}
+	if v, err := p.Get([]byte("42")); err != nil || string(v) != "42" {
+		t.Errorf("expected 42 got %s, %v", v, err)
+	}
+	if v, remaining, err := s.GetTTL([]byte(p.Partition([]byte("ttl"))), []byte("ttl")); err != nil || string(v) != "v" || remaining <= 0 {
+		t.Errorf("expected the expiry to be kept got %s, %s, %v", v, remaining, err)
+	}
+	if err := p.Put([]byte("new"), []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err := p.Delete([]byte("42")); err != nil {
+		t.Error(err)
+	}
+	if _, err := p.Get([]byte("42")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	if v, err := p.Get([]byte("new")); err != nil || string(v) != "1" {
+		t.Errorf("expected 1 got %s, %v", v, err)
+	}
+}
+
+func TestSplitImmutableNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithImmutableNamespace("imm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("imm", []byte("a"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.SplitNamespace("imm", 2, nil); !errors.Is(err, ErrImmutable) {
+		t.Errorf("expected error %s, got %v", ErrImmutable, err)
+	}
+	if v, err := s.Get([]byte("imm"), []byte("a")); err != nil || string(v) != "v" {
+		t.Errorf("expected v got %s, %v", v, err)
+	}
+}
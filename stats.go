@@ -0,0 +1,72 @@
+package gostore
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Stats are the statistics of the store.
+type Stats struct {
+	// DB are the statistics of the bolt database.
+	DB         bolt.Stats
+	Namespaces map[string]NamespaceStats
+	Memory     MemoryStats
+}
+
+// NamespaceStats are the statistics of one namespace.
+type NamespaceStats struct {
+	// Keys is the number of live records.
+	Keys int
+	// Expired is the number of expired records not collected yet.
+	Expired int
+	// Size is the number of bytes of keys and encoded values, expired
+	// records included.
+	Size int64
+}
+
+// Count returns the number of live records of a namespace.
+func (s *Store) Count(namespace []byte) (int, error) {
+	var n int
+	err := s.view(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(namespace); bucket != nil {
+			n = bucketStats(bucket, time.Now()).Keys
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Stats returns the statistics of the database and of every namespace. It
+// reads every record, so it takes time on large stores.
+func (s *Store) Stats() (Stats, error) {
+	stats := Stats{Namespaces: make(map[string]NamespaceStats), Memory: s.MemoryStats()}
+	err := s.view(func(tx *bolt.Tx) error {
+		stats.DB = s.db.Stats()
+		now := time.Now()
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if !isInternal(string(name)) {
+				stats.Namespaces[string(name)] = bucketStats(bucket, now)
+			}
+			return nil
+		})
+	})
+	return stats, err
+}
+
+func bucketStats(bucket *bolt.Bucket, now time.Time) NamespaceStats {
+	var stats NamespaceStats
+	c := bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil {
+			continue
+		}
+		stats.Size += int64(len(k) + len(v))
+		if expire, ok := expireOf(v); ok && !expire.IsZero() && now.After(expire) {
+			stats.Expired++
+		} else {
+			stats.Keys++
+		}
+	}
+	return stats
+}
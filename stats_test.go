@@ -0,0 +1,52 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("ns")
+	if err := s.Put(string(ns), []byte("a"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("b"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL(ns, []byte("c"), []byte("value"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+
+	if n, err := s.Count(ns); err != nil || n != 2 {
+		t.Errorf("expected 2 got %d, %v", n, err)
+	}
+	if n, err := s.Count([]byte("missing")); err != nil || n != 0 {
+		t.Errorf("expected 0 got %d, %v", n, err)
+	}
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := stats.Namespaces[string(ns)]
+	// each record is a 1 byte key and a 17 byte value
+	expected := NamespaceStats{Keys: 2, Expired: 1, Size: 3 * 18}
+	if got != expected {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+	if stats.DB.TxN == 0 {
+		t.Errorf("expected read transactions got %d", stats.DB.TxN)
+	}
+}
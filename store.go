@@ -2,15 +2,24 @@ package gostore
 
 import (
 	"bytes"
+	"context"
 	"encoding"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"golang.org/x/sync/singleflight"
 
 	bolt "go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -18,6 +27,9 @@ const (
 	_defaultBucket     = "default"
 	_bucketTTL         = "ttl"
 	_defaultNumRetries = 3
+	// _internalPrefix marks buckets used by the store itself. Text names
+	// cannot start with a NUL byte, so it does not collide with namespaces.
+	_internalPrefix = "\x00"
 )
 
 var (
@@ -31,15 +43,88 @@ var (
 	// ErrBadValue is returned when the value supplied to the Put method
 	// is nil.
 	ErrBadValue = errors.New("bad value")
+
+	// ErrCorrupted is returned when a stored record cannot be decoded.
+	ErrCorrupted = errors.New("corrupted value")
+
+	// ErrClosed is returned when background work is started on a closed
+	// store.
+	ErrClosed = errors.New("store closed")
 )
 
 // Option the tracer provider option
 type Option func(*option) error
 
 type option struct {
-	numRetries   uint8
-	readOnly     bool
-	maxCacheSize int // maxCacheSize is the maximum number of items in the LRU cache.
+	numRetries    uint8
+	readRetries   uint8
+	readOnly      bool
+	maxCacheSize  int // maxCacheSize is the maximum number of items in the LRU cache.
+	maxCacheBytes int64
+	openTimeout   time.Duration // openTimeout is how long to wait for the file lock.
+	quotas        map[string]Quota
+	failpoints    Failpoints
+	gcInterval    time.Duration
+	gcPacing      GCPacing
+	memoryLimit   int64
+	// largeValueSize is the size from which values go to the large value cache.
+	largeValueSize int
+	idempotencyTTL time.Duration
+	immutable      map[string]struct{}
+	changeLog      bool
+	changeLogSize  int
+	webhooks       []webhookConfig
+	maxTxSize      int
+	codec          Codec
+	cacheShards    int
+	fixedExpiry    bool
+	autoCompact    float64
+	sync           bool
+	shadow         *shadowConfig
+	dualWrite      KVStore
+	// initialMmapSize and pageSize are passed to bolt
+	initialMmapSize int
+	pageSize        int
+	// breakerThreshold and breakerCoolDown configure WithCircuitBreaker
+	breakerThreshold int
+	breakerCoolDown  time.Duration
+	cacheNamespaces  map[string]struct{}
+	ephemeralWrites  bool
+	noOverwrite      map[string]struct{}
+	hooks            Hooks
+	tracer           trace.Tracer
+	corruptPolicy    CorruptPolicy
+	corruptBackups   string
+	logger           *slog.Logger
+	memoryBudget     int64
+	hashedKeys       bool
+	bootstrapURL     string
+	topicCap         int
+	sizeSampling     bool
+}
+
+var _defaultBucketName = []byte(_defaultBucket)
+
+// ErrInternalNamespace is returned for a write to a namespace starting
+// with a NUL byte, which the store keeps for itself.
+var ErrInternalNamespace = errors.New("namespace is internal")
+
+// isInternal reports whether a bucket is used by the store itself rather
+// than being a namespace
+func isInternal(name string) bool {
+	return strings.HasPrefix(name, _internalPrefix)
+}
+
+// IsInternalNamespace reports whether name is kept by the store for
+// itself, so frontends can refuse it to their clients
+func IsInternalNamespace(name string) bool {
+	return isInternal(name)
+}
+
+// KV is a key and its value
+type KV struct {
+	Key   []byte
+	Value []byte
 }
 
 type valueT struct {
@@ -52,56 +137,135 @@ func (v *valueT) isExpired() bool {
 }
 
 func (v valueT) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
+	buf := make([]byte, 4+len(v.Value)+8)
+	binary.LittleEndian.PutUint32(buf, uint32(len(v.Value)))
+	copy(buf[4:], v.Value)
+	binary.LittleEndian.PutUint64(buf[4+len(v.Value):], uint64(v.Expire.Unix()))
+	return buf, nil
+}
 
-	if err := binary.Write(buf, binary.LittleEndian, int32(len(v.Value))); err != nil {
-		return nil, err
+func (v *valueT) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return io.ErrUnexpectedEOF
 	}
-	if _, err := buf.Write(v.Value); err != nil {
-		return nil, err
+	length := int32(binary.LittleEndian.Uint32(data))
+	if length < 0 || len(data)-4-8 < int(length) {
+		return io.ErrUnexpectedEOF
 	}
+	v.Value = append([]byte(nil), data[4:4+length]...)
+	v.Expire = time.Unix(int64(binary.LittleEndian.Uint64(data[4+length:])), 0)
+	return nil
+}
 
-	if err := binary.Write(buf, binary.LittleEndian, v.Expire.Unix()); err != nil {
-		return nil, err
+// WithNumRetries defines service name
+func WithNumRetries(n uint8) Option {
+	return func(o *option) error {
+		o.numRetries = n
+		return nil
 	}
+}
 
-	return buf.Bytes(), nil
+// WithReadRetries sets how many times a read transaction is attempted, 3
+// by default. Only failures to begin the transaction are retried; errors
+// of the read itself are its answer and returned as they are.
+func WithReadRetries(n uint8) Option {
+	return func(o *option) error {
+		o.readRetries = n
+		return nil
+	}
 }
 
-func (v *valueT) UnmarshalBinary(data []byte) error {
-	buf := bytes.NewReader(data)
+// WithMaxCacheSize sets the maximum number of items in the LRU cache.
+func WithMaxCacheSize(maxCacheSize int) Option {
+	return func(o *option) error {
+		o.maxCacheSize = maxCacheSize
+		return nil
+	}
+}
 
-	var length int32
-	if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
-		return err
+// WithMaxCacheBytes sets the maximum number of bytes of keys and values in
+// the LRU cache. Values larger than the limit are not cached. Without
+// WithMaxCacheSize, the number of items is only bounded by the bytes.
+func WithMaxCacheBytes(n int64) Option {
+	return func(o *option) error {
+		o.maxCacheBytes = n
+		return nil
 	}
-	v.Value = make([]byte, length)
-	if _, err := buf.Read(v.Value); err != nil {
-		return err
+}
+
+// WithCacheShards splits the LRU cache into n shards to reduce lock
+// contention between concurrent readers. The default is a single shard.
+func WithCacheShards(n int) Option {
+	return func(o *option) error {
+		o.cacheShards = n
+		return nil
 	}
+}
 
-	// 读取 Expire 时间戳
-	var expire int64
-	if err := binary.Read(buf, binary.LittleEndian, &expire); err != nil {
-		return err
+// CacheStats returns the statistics of the LRU cache, summed over its
+// shards
+func (s *Store) CacheStats() CacheStats {
+	var stats CacheStats
+	for _, shard := range s.CacheShardStats() {
+		stats.Hits += shard.Hits
+		stats.Misses += shard.Misses
+		stats.Evictions += shard.Evictions
+		stats.Expirations += shard.Expirations
+		stats.Entries += shard.Len
+		stats.Bytes += shard.Bytes
 	}
-	v.Expire = time.Unix(expire, 0)
+	return stats
+}
 
-	return nil
+// CacheShardStats returns the statistics of each LRU cache shard, or nil if
+// the cache is disabled.
+func (s *Store) CacheShardStats() []ShardStats {
+	if s.lru == nil {
+		return nil
+	}
+	return s.lru.Stats()
 }
 
-// WithNumRetries defines service name
-func WithNumRetries(n uint8) Option {
+// WithOpenTimeout sets how long Open waits for the file lock held by
+// another process. Zero, the default, waits forever.
+func WithOpenTimeout(d time.Duration) Option {
 	return func(o *option) error {
-		o.numRetries = n
+		o.openTimeout = d
 		return nil
 	}
 }
 
-// WithMaxCacheSize sets the maximum number of items in the LRU cache.
-func WithMaxCacheSize(maxCacheSize int) Option {
+// WithInitialMmapSize sets the initial size of the memory map in bytes.
+// A map large enough for the whole file keeps writers from waiting on
+// readers while it grows.
+func WithInitialMmapSize(n int) Option {
 	return func(o *option) error {
-		o.maxCacheSize = maxCacheSize
+		o.initialMmapSize = n
+		return nil
+	}
+}
+
+// WithPageSize sets the page size of a new database file. It is ignored
+// for existing files.
+func WithPageSize(n int) Option {
+	return func(o *option) error {
+		o.pageSize = n
+		return nil
+	}
+}
+
+// WithSync syncs the database file to disk as each write commits. By
+// default the file is only synced by Sync and Close, so a crash can lose
+// committed writes.
+func WithSync() Option {
+	return WithNoSync(false)
+}
+
+// WithNoSync sets whether commits skip syncing the database file, the
+// default
+func WithNoSync(noSync bool) Option {
+	return func(o *option) error {
+		o.sync = !noSync
 		return nil
 	}
 }
@@ -116,10 +280,49 @@ func WithReadOnly() Option {
 
 // Store is KVStore implementation based bolt DB
 type Store struct {
-	opt   *option
-	db    *bolt.DB
-	lru   *lru
-	group singleflight.Group
+	opt  atomic.Pointer[option]
+	path string
+	// dbMu is held exclusively while the database file is swapped
+	dbMu     sync.RWMutex
+	db       *bolt.DB
+	boltOpts bolt.Options
+	lru      *shardedLRU
+	large    *weakCache
+	group    singleflight.Group
+	breakers breakers
+	quotas   map[string]*quotaState
+	// listeners are called with every committed change
+	listeners []func(Change)
+	dual      *dualWriter
+	// overlay holds ephemeral writes of a read-only store
+	overlay  *overlay
+	views    views
+	watchers watchers
+	frozen   frozen
+	topics   topics
+	sizes    sizeSamples
+
+	gcMu   sync.Mutex
+	gcNext gcCursor
+
+	reconfigMu sync.Mutex
+	gcReset    chan struct{}
+
+	expiredMu     sync.Mutex
+	expired       chan KeyEvent
+	expiredClosed bool
+
+	shadowSem        chan struct{}
+	shadowReads      atomic.Uint64
+	shadowMismatches atomic.Uint64
+
+	bgMu       sync.Mutex
+	gcRunning  bool
+	compacting bool
+	memRunning bool
+	done       chan struct{}
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
 }
 
 // Open opens a store with the given config
@@ -127,9 +330,9 @@ func Open(DbPath string, opts ...Option) (*Store, error) {
 	var (
 		err error
 		opt option
-		lru *lru
+		lru *shardedLRU
 	)
-	boltOpts := bolt.DefaultOptions
+	boltOpts := *bolt.DefaultOptions
 	for _, o := range opts {
 		if err = o(&opt); err != nil {
 			return nil, err
@@ -138,29 +341,141 @@ func Open(DbPath string, opts ...Option) (*Store, error) {
 	if opt.numRetries == 0 {
 		opt.numRetries = _defaultNumRetries
 	}
-	if opt.maxCacheSize > 0 {
-		lru = newLRU(opt.maxCacheSize)
+	if opt.readRetries == 0 {
+		opt.readRetries = _defaultNumRetries
+	}
+	if opt.maxCacheSize > 0 || opt.maxCacheBytes > 0 || opt.memoryBudget > 0 {
+		lru = newShardedLRU(cacheCapacity(&opt), opt.cacheShards)
+		lru.SetMaxBytes(opt.maxCacheBytes)
+		if onEvict := opt.hooks.OnEvict; onEvict != nil {
+			lru.OnEvict(func(k string) { onEvict(splitCacheKey(k)) })
+		}
 	}
 	boltOpts.ReadOnly = opt.readOnly
-	boltOpts.NoSync = true
+	boltOpts.Timeout = opt.openTimeout
+	boltOpts.InitialMmapSize = opt.initialMmapSize
+	boltOpts.PageSize = opt.pageSize
+	boltOpts.NoSync = !opt.sync
 	boltOpts.NoFreelistSync = true
 
-	db, err := bolt.Open(DbPath, _fileMode, boltOpts)
+	if opt.bootstrapURL != "" {
+		if err := bootstrap(opt.bootstrapURL, DbPath); err != nil {
+			return nil, fmt.Errorf("failed to bootstrap %s: %w", DbPath, err)
+		}
+	}
+	db, err := bolt.Open(DbPath, _fileMode, &boltOpts)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkKeyMode(db, &opt); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &Store{
+		path:      DbPath,
+		db:        db,
+		boltOpts:  boltOpts,
+		lru:       lru,
+		group:     singleflight.Group{},
+		quotas:    newQuotaStates(opt.quotas),
+		done:      make(chan struct{}),
+		shadowSem: make(chan struct{}, _maxShadowReads),
+		gcReset:   make(chan struct{}, 1),
+	}
+	s.opt.Store(&opt)
+	if opt.largeValueSize > 0 {
+		s.large = newWeakCache()
+	}
+	if opt.readOnly && opt.ephemeralWrites {
+		s.overlay = newOverlay()
+	}
+	s.rebalanceMemory()
+	if !opt.readOnly {
+		for _, cfg := range opt.webhooks {
+			s.startWebhook(cfg)
+		}
+		if opt.dualWrite != nil {
+			s.startDualWrite(opt.dualWrite)
+		}
+		// leftovers of interrupted maintenance, best-effort
+		_, _ = s.CleanTemp()
+	}
+	s.startBackground()
+	return s, nil
+}
+
+// OpenAny tries each path in order and returns the first store that opens
+// and passes a consistency check. Missing files are skipped; Path reports
+// which file was used.
+func OpenAny(paths ...string) (*Store, error) {
+	if len(paths) == 0 {
+		return nil, os.ErrNotExist
+	}
+	var errs []error
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s, err := Open(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to open %s: %w", path, err))
+			continue
+		}
+		if err := s.check(); err != nil {
+			s.Close()
+			errs = append(errs, fmt.Errorf("failed to check %s: %w", path, err))
+			continue
+		}
+		return s, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Path returns the path of the database file
+func (s *Store) Path() string {
+	return s.path
+}
 
-	return &Store{
-		db:    db,
-		opt:   &opt,
-		lru:   lru,
-		group: singleflight.Group{},
-	}, nil
+// check verifies the page structure of the database file
+func (s *Store) check() error {
+	return s.view(checkTx)
 }
 
-// Close closes the store
-func (s *Store) Close() error {
-	return s.db.Close()
+func checkTx(tx *bolt.Tx) error {
+	var errs []error
+	for err := range tx.Check() {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops background work and closes the store
+func (s *Store) Close() (err error) {
+	s.closeOnce.Do(func() {
+		s.bgMu.Lock()
+		close(s.done)
+		s.bgMu.Unlock()
+		s.wg.Wait()
+		s.closeExpired()
+		s.closeWatchers()
+		s.dbMu.Lock()
+		defer s.dbMu.Unlock()
+		if !s.opt.Load().readOnly {
+			// writes are not synced as they commit
+			err = s.db.Sync()
+		}
+		err = errors.Join(err, s.db.Close())
+	})
+	return err
+}
+
+// Sync flushes committed writes to disk
+func (s *Store) Sync() error {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.db.Sync()
 }
 
 // Put inserts a <key, value> record
@@ -170,72 +485,344 @@ func (s *Store) Put(namespace string, key, value []byte) (err error) {
 
 // PutWithTTL inserts a <key, value> record with TTL
 func (s *Store) PutWithTTL(namespace, key, value []byte, ttl int64) (err error) {
-	for c := uint8(0); c < s.opt.numRetries; c++ {
-		if err = s.db.Update(func(tx *bolt.Tx) error {
-			bucket, err := tx.CreateBucketIfNotExists(namespace)
+	return s.PutContext(context.Background(), namespace, key, value, ttl)
+}
+
+// PutContext is PutWithTTL under ctx: its span joins the trace of ctx, and
+// it fails without writing if ctx is done.
+func (s *Store) PutContext(ctx context.Context, namespace, key, value []byte, ttl int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.putWithExpire(ctx, namespace, key, value, newValueT(value, ttl).Expire)
+}
+
+// putWithExpire inserts a <key, value> record expiring at expire
+func (s *Store) putWithExpire(ctx context.Context, namespace, key, value []byte, expire time.Time) (err error) {
+	_, end := s.trace(ctx, "gostore.Put", namespace, key)
+	defer func() { end(err) }()
+	dk := s.diskKey(key)
+	if s.overlay != nil {
+		buf, _ := valueT{Value: value, Expire: expire}.MarshalBinary()
+		err = s.putOverlay(namespace, dk, buf)
+	} else {
+		err = s.update(func(tx *bolt.Tx) error {
+			buf, err := valueT{Value: value, Expire: expire}.MarshalBinary()
 			if err != nil {
 				return err
 			}
-			newvalue := newValueT(value, ttl)
-			buf, err := newvalue.MarshalBinary()
-			if err != nil {
+			if err := s.opt.Load().failpoints.AfterMarshal.eval(); err != nil {
 				return err
 			}
+			return s.put(tx, namespace, dk, buf)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+	if ck, ok := s.readThrough(namespace, dk); ok {
+		s.cacheAdd(ck, bytes.Clone(value), expire, PriorityNormal)
+	}
+	return nil
+}
+
+// view runs fn in a read transaction, retrying up to readRetries times to
+// begin it
+func (s *Store) view(fn func(tx *bolt.Tx) error) error {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	opt := s.opt.Load()
+	for c := uint8(1); ; c++ {
+		err := opt.failpoints.BeforeRead.eval()
+		if err == nil {
+			var tx *bolt.Tx
+			if tx, err = s.db.Begin(false); err == nil {
+				return runView(tx, fn)
+			}
+		}
+		if c >= opt.readRetries || errors.Is(err, bolt.ErrDatabaseNotOpen) {
+			return err
+		}
+		s.logger().Warn("gostore: retrying read transaction", "attempt", c, "error", err)
+	}
+}
+
+// runView runs fn in tx and rolls it back, as bolt's View does
+func runView(tx *bolt.Tx, fn func(tx *bolt.Tx) error) error {
+	defer func() { _ = tx.Rollback() }()
+	return fn(tx)
+}
 
-			return bucket.Put(key, buf)
-		}); err == nil {
-			break
+// update runs fn in a write transaction, retrying up to numRetries times
+func (s *Store) update(fn func(tx *bolt.Tx) error) (err error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	retries := s.opt.Load().numRetries
+	for c := uint8(0); c < retries; c++ {
+		var wtx *bolt.Tx
+		err = s.db.Update(func(tx *bolt.Tx) error {
+			wtx = tx
+			if err := fn(tx); err != nil {
+				return err
+			}
+			return s.opt.Load().failpoints.BeforeCommit.eval()
+		})
+		if wtx != nil {
+			s.discardQuotas(wtx)
+			if s.dual != nil {
+				s.dual.finish(wtx, false)
+			}
+		}
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if c+1 < retries {
+			s.logger().Warn("gostore: retrying transaction", "attempt", c+1, "error", err)
 		}
 	}
 	if err != nil {
-		err = fmt.Errorf("failed to put key %s: %w", key, err)
+		s.logger().Error("gostore: transaction failed", "attempts", retries, "error", err)
 	}
-
 	return err
 }
 
+// retryable reports whether a failed write may succeed when tried again
+func retryable(err error) bool {
+	var p permanentError
+	return !errors.Is(err, ErrQuotaExceeded) && !errors.Is(err, ErrImmutable) &&
+		!errors.Is(err, ErrKeyExists) && !errors.Is(err, ErrFrozen) &&
+		!errors.Is(err, ErrInternalNamespace) && !errors.Is(err, ErrViewReadOnly) &&
+		!errors.Is(err, bolt.ErrBucketNotFound) && !errors.As(err, &p)
+}
+
+// permanentError marks an error that retrying the transaction won't fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// put stores an encoded value in the namespace bucket, running the
+// BeforePut and AfterPut hooks around it
+func (s *Store) put(tx *bolt.Tx, namespace, key, buf []byte) (err error) {
+	if isInternal(string(namespace)) {
+		return ErrInternalNamespace
+	}
+	if s.isView(string(namespace)) {
+		return ErrViewReadOnly
+	}
+	hooks := s.opt.Load().hooks
+	if hooks.BeforePut != nil || hooks.AfterPut != nil {
+		value := recordValue(buf)
+		if hooks.BeforePut != nil {
+			if err := hooks.BeforePut(namespace, key, value); err != nil {
+				return permanentError{err}
+			}
+		}
+		if hooks.AfterPut != nil {
+			defer func() {
+				if err != nil {
+					hooks.AfterPut(namespace, key, value, err)
+					return
+				}
+				tx.OnCommit(func() { hooks.AfterPut(namespace, key, value, nil) })
+			}()
+		}
+	}
+	if s.isFrozen(namespace) {
+		return ErrFrozen
+	}
+	bucket, err := tx.CreateBucketIfNotExists(namespace)
+	if err != nil {
+		return err
+	}
+	if s.isImmutable(namespace) && bucket.Get(key) != nil {
+		return ErrImmutable
+	}
+	if err := s.checkOverwrite(namespace, bucket.Get(key)); err != nil {
+		return err
+	}
+	evicted, err := s.checkQuota(tx, bucket, namespace, key, buf)
+	if err != nil {
+		return err
+	}
+	onDelete := s.opt.Load().hooks.OnDelete
+	for _, k := range evicted {
+		s.invalidate(tx, namespace, k)
+		if onDelete != nil {
+			tx.OnCommit(func() { onDelete(namespace, k) })
+		}
+	}
+	if err := bucket.Put(key, buf); err != nil {
+		return err
+	}
+	s.invalidate(tx, namespace, key)
+	return s.changed(tx, OpPut, namespace, key, buf)
+}
+
+// delete removes a key from the namespace bucket
+func (s *Store) delete(tx *bolt.Tx, namespace, key []byte) error {
+	if isInternal(string(namespace)) {
+		return ErrInternalNamespace
+	}
+	if s.isView(string(namespace)) {
+		return ErrViewReadOnly
+	}
+	if s.isFrozen(namespace) {
+		return ErrFrozen
+	}
+	bucket := tx.Bucket(namespace)
+	if bucket == nil {
+		return nil
+	}
+	old := bucket.Get(key)
+	if old == nil {
+		return nil
+	}
+	if s.isImmutable(namespace) {
+		return ErrImmutable
+	}
+	if q := s.quotas[string(namespace)]; q != nil {
+		q.current(tx, bucket)
+		q.add(tx, -1, -int64(len(key)+len(old)))
+	}
+	if err := bucket.Delete(key); err != nil {
+		return err
+	}
+	s.invalidate(tx, namespace, key)
+	if h := s.opt.Load().hooks.OnDelete; h != nil {
+		tx.OnCommit(func() { h(namespace, key) })
+	}
+	return s.changed(tx, OpDelete, namespace, key, old)
+}
+
+// recordValue returns the value of an encoded valueT, or nil if buf is too
+// short to hold one
+func recordValue(buf []byte) []byte {
+	if len(buf) < 12 {
+		return nil
+	}
+	return buf[4 : len(buf)-8]
+}
+
 // Get fetches a value by key
-func (s *Store) Get(namespace, key []byte) ([]byte, error) {
-	valT, err := s.get(namespace, key)
+func (s *Store) Get(namespace, key []byte) (value []byte, err error) {
+	return s.GetContext(context.Background(), namespace, key)
+}
+
+// GetContext is Get under ctx: its span joins the trace of ctx, and it
+// fails without reading if ctx is done.
+func (s *Store) GetContext(ctx context.Context, namespace, key []byte) (value []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, end := s.trace(ctx, "gostore.Get", namespace, key)
+	defer func() { end(err) }()
+	defer func() {
+		if h := s.opt.Load().hooks.AfterGet; h != nil {
+			h(namespace, key, value, err)
+		}
+		s.shadowGet(namespace, key, value, err)
+	}()
+	dk := s.diskKey(key)
+	ck, cached := s.readThrough(namespace, dk)
+	if cached {
+		if v, ok := s.cacheGet(ck); ok {
+			s.sampleSize(namespace, len(v))
+			return bytes.Clone(v), nil
+		}
+	}
+	valT, err := s.get(namespace, dk)
 	if err != nil {
 		return nil, err
 	}
+	s.sampleSize(namespace, len(valT.Value))
+	if cached && !valT.isExpired() {
+		s.cacheAdd(ck, bytes.Clone(valT.Value), valT.Expire, PriorityNormal)
+	}
 	if valT.Expire.IsZero() {
 		return valT.Value, nil
 	}
 	if time.Now().After(valT.Expire) {
+		s.notifyExpired(namespace, dk, valT.Expire)
 		return nil, ErrKeyExpired
 	}
 	return valT.Value, err
 }
 
+// GetView calls fn with the value of key without copying it. The slice
+// points into the memory map and is only valid until fn returns.
+func (s *Store) GetView(namespace, key []byte, fn func(value []byte) error) error {
+	key = s.diskKey(key)
+	return s.view(func(tx *bolt.Tx) error {
+		val, ok := s.overlay.get(namespace, key)
+		if !ok {
+			if bucket := tx.Bucket(namespace); bucket != nil {
+				val = bucket.Get(key)
+			}
+		}
+		if val == nil {
+			return ErrKeyNotFound
+		}
+		expire, ok := expireOf(val)
+		if !ok {
+			return ErrCorrupted
+		}
+		if !expire.IsZero() && time.Now().After(expire) {
+			s.notifyExpired(namespace, key, expire)
+			return ErrKeyExpired
+		}
+		return fn(val[4 : len(val)-8])
+	})
+}
+
 func (s *Store) get(namespace, key []byte) (*valueT, error) {
 	var value = &valueT{}
 	var err error
-	err = s.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket(namespace)
-		if bucket == nil {
-			return ErrKeyNotFound
+	err = s.view(func(tx *bolt.Tx) error {
+		val, ok := s.overlay.get(namespace, key)
+		if !ok {
+			if bucket := tx.Bucket(namespace); bucket != nil {
+				val = bucket.Get(key)
+			}
 		}
-		val := bucket.Get(key)
 		if val == nil {
 			return ErrKeyNotFound
 		}
 
-		err = value.UnmarshalBinary(val)
+		if err := value.UnmarshalBinary(val); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorrupted, err)
+		}
 		return nil
 	})
+	if errors.Is(err, ErrCorrupted) && s.opt.Load().corruptPolicy != CorruptFail && s.overlay == nil {
+		return s.repair(namespace, key)
+	}
 	return value, err
 }
 
 // Delete deletes a record by key
-func (s *Store) Delete(namespace string, key []byte) error {
-	return s.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(namespace))
-		if bucket == nil {
-			return nil
+func (s *Store) Delete(namespace string, key []byte) (err error) {
+	return s.DeleteContext(context.Background(), namespace, key)
+}
+
+// DeleteContext is Delete under ctx: its span joins the trace of ctx, and
+// it fails without deleting if ctx is done.
+func (s *Store) DeleteContext(ctx context.Context, namespace string, key []byte) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, end := s.trace(ctx, "gostore.Delete", []byte(namespace), key)
+	defer func() { end(err) }()
+	dk := s.diskKey(key)
+	if s.overlay != nil {
+		if err := s.deleteOverlay([]byte(namespace), dk); err != nil {
+			return err
 		}
-		return bucket.Delete(key)
+		s.cacheDelete(cacheKey([]byte(namespace), dk))
+		return nil
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		return s.delete(tx, []byte(namespace), dk)
 	})
 }
 
@@ -246,6 +833,12 @@ func (s *Store) Update(key string, value encoding.BinaryMarshaler) error {
 
 // UpdateWithTTL set value by key with TTL, value must be implement encoding.BinaryMarshaler
 func (s *Store) UpdateWithTTL(key string, value encoding.BinaryMarshaler, ttl int64) error {
+	return s.UpdateWithPriority(key, value, ttl, PriorityNormal)
+}
+
+// UpdateWithPriority set value by key with TTL, the priority decides how early
+// the value is evicted from the LRU cache
+func (s *Store) UpdateWithPriority(key string, value encoding.BinaryMarshaler, ttl int64, p Priority) error {
 	if value == nil {
 		return ErrBadValue
 	}
@@ -256,7 +849,7 @@ func (s *Store) UpdateWithTTL(key string, value encoding.BinaryMarshaler, ttl in
 	if err := s.PutWithTTL([]byte(_defaultBucket), []byte(key), buf, ttl); err != nil {
 		return err
 	}
-	s.tryAddToLRU(key, buf, ttl)
+	s.tryAddToLRU(string(s.diskKey([]byte(key))), buf, ttl, p)
 	return nil
 }
 
@@ -277,34 +870,69 @@ func (s *Store) Load(key string, obj encoding.BinaryUnmarshaler) error {
 	if obj == nil {
 		return ErrBadValue
 	}
-	if s.lru != nil {
-		if v, ok := s.lru.Get(key); ok {
-			return obj.UnmarshalBinary(v)
-		}
+	dk := unsafeBytes(key)
+	if s.opt.Load().hashedKeys {
+		dk = s.diskKey(dk)
+		key = string(dk)
+	}
+	if v, ok := s.cacheGet(key); ok {
+		return obj.UnmarshalBinary(v)
 	}
 
-	valT, err := s.get([]byte(_defaultBucket), []byte(key))
+	valT, err := s.get(_defaultBucketName, dk)
 	if err != nil {
 		return err
 	}
 	if valT.isExpired() {
+		s.notifyExpired([]byte(_defaultBucket), bytes.Clone(dk), valT.Expire)
 		return ErrKeyExpired
 	}
 	return obj.UnmarshalBinary(valT.Value)
 }
 
+// Namespaces returns the names of the namespaces in the store, sorted
+func (s *Store) Namespaces() ([]string, error) {
+	var names []string
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if !isInternal(string(name)) {
+				names = append(names, string(name))
+			}
+			return nil
+		})
+	})
+	return names, err
+}
+
 // DeleteNamespace deletes a namespace
 func (s *Store) DeleteNamespace(namespace string) error {
-	return s.db.Update(func(tx *bolt.Tx) error {
-		return tx.DeleteBucket([]byte(namespace))
+	return s.update(func(tx *bolt.Tx) error {
+		if isInternal(namespace) {
+			return ErrInternalNamespace
+		}
+		if s.isView(namespace) {
+			return ErrViewReadOnly
+		}
+		if s.isImmutable([]byte(namespace)) {
+			return ErrImmutable
+		}
+		if s.isFrozen([]byte(namespace)) {
+			return ErrFrozen
+		}
+		if q := s.quotas[namespace]; q != nil {
+			tx.OnCommit(q.reset)
+		}
+		if err := tx.DeleteBucket([]byte(namespace)); err != nil {
+			return err
+		}
+		tx.OnCommit(func() { s.cachePurge([]byte(namespace)) })
+		return s.changed(tx, OpDeleteNamespace, []byte(namespace), nil, nil)
 	})
 }
 
 // Remove delete a record by key
 func (s *Store) Remove(key string) error {
-	if s.lru != nil {
-		s.lru.Delete(key)
-	}
+	s.cacheDelete(key)
 	return s.Delete(_defaultBucket, []byte(key))
 }
 
@@ -313,47 +941,168 @@ func (s *Store) Memoize(key string, obj encoding.BinaryUnmarshaler, f func() (an
 	return s.MemoizeWithTTL(key, obj, f, 0)
 }
 
-func (s *Store) MemoizeWithTTL(key string, obj encoding.BinaryUnmarshaler, f func() (any, error), ttl int64) error {
-	if err := s.Load(key, obj); err != nil {
-		if err != ErrKeyNotFound && err != ErrKeyExpired {
-			return err
-		}
+func (s *Store) MemoizeWithTTL(key string, obj encoding.BinaryUnmarshaler, f func() (any, error), ttl int64) (err error) {
+	return s.MemoizeContext(context.Background(), key, obj, f, ttl)
+}
 
-		_, err, _ := s.group.Do(key, func() (any, error) {
-			data, innerErr := f()
-			if innerErr != nil {
-				return nil, innerErr
-			}
-			v, ok := data.(encoding.BinaryMarshaler)
-			if !ok {
-				return nil, ErrBadValue
-			}
+// MemoizeContext is MemoizeWithTTL under ctx: its span, and the span of
+// the Put storing a computed value, join the trace of ctx. It fails
+// without loading if ctx is done.
+func (s *Store) MemoizeContext(ctx context.Context, key string, obj encoding.BinaryUnmarshaler, f func() (any, error), ttl int64) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ctx, end := s.trace(ctx, "gostore.Memoize", _defaultBucketName, []byte(key))
+	defer func() { end(err) }()
+	if err := s.Load(key, obj); err != ErrKeyNotFound && err != ErrKeyExpired {
+		return err
+	}
+	v, err, _ := s.group.Do(key, s.refresher(ctx, key, f, ttl))
+	if err != nil {
+		return err
+	}
+	return obj.UnmarshalBinary(v.([]byte))
+}
 
-			buf, err := v.MarshalBinary()
-			if err != nil {
-				return nil, err
-			}
-			if err := s.PutWithTTL([]byte(_defaultBucket), []byte(key), buf, ttl); err != nil {
-				return nil, err
-			}
-			s.tryAddToLRU(key, buf, ttl)
-			return data, obj.UnmarshalBinary(buf)
-		})
+// MemoizeWithin is like MemoizeWithTTL, but if f takes longer than budget
+// it serves the previous value, even if expired, while f finishes in the
+// background. Without a previous value it returns
+// context.DeadlineExceeded.
+func (s *Store) MemoizeWithin(key string, obj encoding.BinaryUnmarshaler, f func() (any, error), ttl int64, budget time.Duration) error {
+	if err := s.Load(key, obj); err != ErrKeyNotFound && err != ErrKeyExpired {
+		return err
+	}
+	ch := s.group.DoChan(key, s.refresher(context.Background(), key, f, ttl))
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+		return obj.UnmarshalBinary(res.Val.([]byte))
+	case <-timer.C:
+	}
+	stale, err := s.get(_defaultBucketName, s.diskKey([]byte(key)))
+	if err == ErrKeyNotFound {
+		return context.DeadlineExceeded
+	}
+	if err != nil {
+		return err
+	}
+	return obj.UnmarshalBinary(stale.Value)
+}
+
+// refresher returns a function computing and storing the memoized value of
+// key under ctx. It returns the encoded value, for every caller to decode.
+func (s *Store) refresher(ctx context.Context, key string, f func() (any, error), ttl int64) func() (any, error) {
+	f = s.guard(key, f)
+	return func() (any, error) {
+		data, err := f()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		v, ok := data.(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, ErrBadValue
+		}
+		buf, err := v.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		expire := s.memoizeExpire(key, ttl)
+		if err := s.putWithExpire(ctx, []byte(_defaultBucket), []byte(key), buf, expire); err != nil {
+			return nil, err
 		}
+		s.cacheAdd(string(s.diskKey([]byte(key))), buf, expire, PriorityNormal)
+		return buf, nil
+	}
+}
+
+// WithFixedExpiry keeps memoized values on the deadlines set when they
+// were first computed. A refreshed value expires at the original expiry,
+// moved forward by whole TTLs past the time of the refresh, instead of one
+// TTL after the refresh.
+func WithFixedExpiry() Option {
+	return func(o *option) error {
+		o.fixedExpiry = true
 		return nil
 	}
-	return nil
 }
 
-func (s *Store) tryAddToLRU(key string, value []byte, ttl int64) {
-	if s.lru == nil {
-		return
+// memoizeExpire returns the expiry of a value memoized now with ttl
+func (s *Store) memoizeExpire(key string, ttl int64) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	now := time.Now()
+	period := time.Duration(ttl) * time.Second
+	if s.opt.Load().fixedExpiry {
+		if old, err := s.get(_defaultBucketName, s.diskKey([]byte(key))); err == nil && !old.Expire.IsZero() {
+			expire := old.Expire
+			if !expire.After(now) {
+				expire = expire.Add((now.Sub(expire)/period + 1) * period)
+			}
+			return expire
+		}
 	}
+	return now.Add(period)
+}
+
+func (s *Store) tryAddToLRU(key string, value []byte, ttl int64, p Priority) {
 	expire := time.Time{}
 	if ttl > 0 {
 		expire = time.Now().Add(time.Duration(ttl) * time.Second)
 	}
-	s.lru.Add(key, expire, value)
+	s.cacheAdd(key, value, expire, p)
+}
+
+// cacheAdd adds a value expiring at expire to the caches
+func (s *Store) cacheAdd(key string, value []byte, expire time.Time, p Priority) {
+	if s.lru == nil && s.large == nil {
+		return
+	}
+	if err := s.opt.Load().failpoints.OnCacheAdd.eval(); err != nil {
+		return
+	}
+	if s.large != nil && len(value) >= s.opt.Load().largeValueSize {
+		// keep huge values from pushing everything else out of the LRU
+		if s.lru != nil {
+			s.lru.Delete(key)
+		}
+		s.large.Add(key, expire, value)
+		return
+	}
+	if s.lru != nil {
+		s.lru.AddWithPriority(key, expire, value, p)
+	}
+}
+
+// cacheGet looks up a key in the LRU cache and then in the large value cache
+func (s *Store) cacheGet(key string) ([]byte, bool) {
+	if s.lru != nil {
+		if v, ok := s.lru.Get(key); ok {
+			return v, true
+		}
+	}
+	if s.large != nil {
+		return s.large.Get(key)
+	}
+	return nil, false
+}
+
+// cacheDelete removes a key from all caches
+func (s *Store) cacheDelete(key string) {
+	if s.lru != nil {
+		s.lru.Delete(key)
+	}
+	if s.large != nil {
+		s.large.Delete(key)
+	}
+}
+
+// unsafeBytes returns the bytes of s without copying. They must not be
+// modified or kept, which makes them good for lookups only.
+func unsafeBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
@@ -1,11 +1,16 @@
 package gostore
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
+
+	bolt "go.etcd.io/bbolt"
 )
 
 func TestOpen(t *testing.T) {
@@ -26,6 +31,42 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestOpenAny(t *testing.T) {
+	corrupt, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(corrupt)
+	if err := os.WriteFile(corrupt, bytes.Repeat([]byte("x"), 8192), 0600); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(backup)
+	s, err := Open(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenAny(corrupt, corrupt+".missing", backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Path() != backup {
+		t.Errorf("expected path %s, got %s", backup, s.Path())
+	}
+
+	if _, err := OpenAny(corrupt); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestPut(t *testing.T) {
 	path, err := tempfile()
 	if err != nil {
@@ -75,6 +116,160 @@ func TestFetch(t *testing.T) {
 	}
 }
 
+func TestSync(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.db.NoSync {
+		t.Error("expected NoSync by default")
+	}
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Sync(); err != nil {
+		t.Error(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path, WithSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.db.NoSync {
+		t.Error("expected WithSync to sync commits")
+	}
+}
+
+func TestBoltOptions(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	os.Remove(path)
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithPageSize(8192), WithInitialMmapSize(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if size := s.db.Info().PageSize; size != 8192 {
+		t.Errorf("expected page size %d, got %d", 8192, size)
+	}
+
+	start := time.Now()
+	if _, err := Open(path, WithOpenTimeout(100*time.Millisecond)); err != bolt.ErrTimeout {
+		t.Errorf("expected error %s, got %v", bolt.ErrTimeout, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected open to time out")
+	}
+}
+
+func TestGetView(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	var got string
+	if err := s.GetView([]byte("test"), []byte("key"), func(v []byte) error {
+		got = string(v)
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+	if got != "value" {
+		t.Errorf("expected value %s, got %s", "value", got)
+	}
+	if err := s.GetView([]byte("test"), []byte("missing"), func([]byte) error { return nil }); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestOptionWithMaxCacheBytes(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheBytes(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Update("small", &T1{Name: "test"}); err != nil {
+		t.Error(err)
+	}
+	if err := s.Update("large", &T1{Name: string(make([]byte, 2048))}); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.lru.Get("small"); !ok {
+		t.Error("expected small to be cached")
+	}
+	if _, ok := s.lru.Get("large"); ok {
+		t.Error("expected large not to be cached")
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(1), WithCacheShards(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.UpdateWithTTL("a", &T1{Name: "a"}, 1); err != nil {
+		t.Error(err)
+	}
+	var v T1
+	_ = s.Load("a", &v)
+	if err := s.Update("b", &T1{Name: "b"}); err != nil {
+		t.Error(err)
+	}
+	_ = s.Load("a", &v)
+	_ = s.Load("b", &v)
+	stats := s.CacheStats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.HitRate() < 0.66 || stats.HitRate() > 0.67 {
+		t.Errorf("expected hit rate 2/3, got %v", stats.HitRate())
+	}
+
+	if err := s.UpdateWithTTL("c", &T1{Name: "c"}, 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+	_ = s.Load("c", &v)
+	if stats := s.CacheStats(); stats.Expirations != 1 || stats.Entries != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
 func TestOptionWithMaxCacheSize(t *testing.T) {
 	path, err := tempfile()
 	if err != nil {
@@ -167,6 +362,36 @@ func TestDeleteNamespace(t *testing.T) {
 
 }
 
+func TestNamespaces(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithChangeLog(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, ns := range []string{"users", "orders", "test"} {
+		if err := s.Put(ns, []byte("a"), []byte("aa")); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.DeleteNamespace("test"); err != nil {
+		t.Error(err)
+	}
+	names, err := s.Namespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the change log bucket is internal
+	if fmt.Sprint(names) != "[orders users]" {
+		t.Errorf("expected [orders users] got %v", names)
+	}
+}
+
 func TestUpdateLoadRemove(t *testing.T) {
 	path, err := tempfile()
 	if err != nil {
@@ -317,6 +542,104 @@ func TestMemoizeWithTTL(t *testing.T) {
 
 }
 
+func TestMemoizeWithin(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	slow := func(name string) func() (any, error) {
+		return func() (any, error) {
+			time.Sleep(500 * time.Millisecond)
+			return &T1{Name: name}, nil
+		}
+	}
+	var v T1
+	if err := s.MemoizeWithin("test", &v, func() (any, error) { return &T1{Name: "v1"}, nil }, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Second)
+	if err := s.MemoizeWithin("test", &v, slow("v2"), 1, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "v1" {
+		t.Errorf("expected stale value v1, got %s", v.Name)
+	}
+	waitFor(t, func() bool {
+		return s.Load("test", &v) == nil && v.Name == "v2"
+	})
+
+	if err := s.MemoizeWithin("missing", &v, slow("v"), 1, 50*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("expected error %s, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestMemoizeWithFixedExpiry(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithFixedExpiry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	f := func() (any, error) { return &T1{Name: "test"}, nil }
+	if err := s.MemoizeWithTTL("test", &T1{}, f, 2); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.get(_defaultBucketName, []byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Until(first.Expire) + 100*time.Millisecond)
+	if err := s.MemoizeWithTTL("test", &T1{}, f, 2); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.get(_defaultBucketName, []byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := first.Expire.Add(2 * time.Second); !second.Expire.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, second.Expire)
+	}
+}
+
+func TestLoadHitAllocs(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Update("test", &T1{Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	var raw rawValue
+	f := func() (any, error) { return nil, nil }
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := s.Memoize("test", &raw, f); err != nil {
+			t.Error(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocs got %v", allocs)
+	}
+}
+
 func BenchmarkStoreWithCache(b *testing.B) {
 	path, err := tempfile()
 	if err != nil {
@@ -351,6 +674,101 @@ func BenchmarkStoreWithCache(b *testing.B) {
 			}
 		}
 	})
+	// raw decodes without allocating, so only the store's own allocations
+	// are reported
+	var raw rawValue
+	b.Run("LoadHit", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := s.Load("test", &raw); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.Run("MemoizeHit", func(b *testing.B) {
+		f := func() (any, error) { return value, nil }
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := s.Memoize("test", &raw, f); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func BenchmarkStoreWithoutCache(b *testing.B) {
+	path, err := tempfile()
+	if err != nil {
+		b.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Update("test", &T1{Name: "test"}); err != nil {
+		b.Fatal(err)
+	}
+	var raw rawValue
+	b.Run("Load", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := s.Load("test", &raw); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGet(b *testing.B) {
+	path, err := tempfile()
+	if err != nil {
+		b.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+
+	ns, key := []byte("ns"), []byte("blob")
+	if err := s.Put("ns", key, make([]byte, 64<<10)); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(64 << 10)
+		for i := 0; i < b.N; i++ {
+			if _, err := s.Get(ns, key); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.Run("GetView", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(64 << 10)
+		for i := 0; i < b.N; i++ {
+			if err := s.GetView(ns, key, func([]byte) error { return nil }); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+type rawValue struct {
+	buf [64]byte
+	n   int
+}
+
+func (r *rawValue) UnmarshalBinary(data []byte) error {
+	r.n = copy(r.buf[:], data)
+	return nil
 }
 
 func tempfile() (string, error) {
@@ -361,3 +779,69 @@ func tempfile() (string, error) {
 	return tempFile.Name(), tempFile.Close()
 
 }
+
+func TestReadRetries(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	errInjected := errors.New("injected")
+	failures := 0
+	s, err := Open(path, WithReadRetries(3), WithFailpoints(Failpoints{BeforeRead: func() error {
+		if failures > 0 {
+			failures--
+			return errInjected
+		}
+		return nil
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+
+	failures = 2
+	if v, err := s.Get([]byte("test"), []byte("key")); err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+	failures = 3
+	if _, err := s.Get([]byte("test"), []byte("key")); !errors.Is(err, errInjected) {
+		t.Errorf("expected error %s, got %v", errInjected, err)
+	}
+	failures = 0
+	if _, err := s.Get([]byte("test"), []byte("missing")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestInternalNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithChangeLog(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put(_changesBucket, []byte("key"), []byte("value")); !errors.Is(err, ErrInternalNamespace) {
+		t.Errorf("expected error %s, got %v", ErrInternalNamespace, err)
+	}
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete(_changesBucket, seqKey(1)); !errors.Is(err, ErrInternalNamespace) {
+		t.Errorf("expected error %s, got %v", ErrInternalNamespace, err)
+	}
+	if err := s.DeleteNamespace(_changesBucket); !errors.Is(err, ErrInternalNamespace) {
+		t.Errorf("expected error %s, got %v", ErrInternalNamespace, err)
+	}
+	if changes, err := s.ChangesSince(0, 0); err != nil || len(changes) != 1 {
+		t.Errorf("expected 1 change got %d, %v", len(changes), err)
+	}
+}
@@ -0,0 +1,212 @@
+package gostore
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FS returns a read-only fs.FS view of the store, with namespaces as
+// directories and keys as files. Keys containing slashes appear as nested
+// directories. Expired records and internal namespaces are hidden, and
+// ephemeral writes are seen over the file. Hashed keys have no paths, so
+// with WithHashedKeys every Open fails with ErrHashedKeys.
+func (s *Store) FS() fs.FS {
+	return storeFS{s}
+}
+
+type storeFS struct {
+	s *Store
+}
+
+// Open implements fs.FS
+func (f storeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if f.s.opt.Load().hashedKeys {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrHashedKeys}
+	}
+	var file fs.File
+	err := f.s.view(func(tx *bolt.Tx) error {
+		if name == "." {
+			file = f.root(tx)
+			return nil
+		}
+		ns, key, _ := strings.Cut(name, "/")
+		bucket := tx.Bucket([]byte(ns))
+		overlaid := f.s.overlay.namespace([]byte(ns))
+		if (bucket == nil && !hasLive(overlaid)) || isInternal(ns) {
+			return fs.ErrNotExist
+		}
+		if key != "" {
+			v, ok := overlaid[key]
+			if !ok && bucket != nil {
+				v = bucket.Get([]byte(key))
+			}
+			if v != nil {
+				var value valueT
+				if err := value.UnmarshalBinary(v); err != nil || value.isExpired() {
+					return fs.ErrNotExist
+				}
+				file = &fsFile{
+					info:   fsInfo{name: pathBase(name), size: int64(len(value.Value))},
+					Reader: bytes.NewReader(value.Value),
+				}
+				return nil
+			}
+			key += "/"
+		}
+		entries := dirEntries(bucket, overlaid, key)
+		if key != "" && len(entries) == 0 {
+			return fs.ErrNotExist
+		}
+		file = &fsDir{info: fsInfo{name: pathBase(name), dir: true}, entries: entries}
+		return nil
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return file, nil
+}
+
+func (f storeFS) root(tx *bolt.Tx) fs.File {
+	names := f.s.overlay.namespaces()
+	_ = tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		names = append(names, string(name))
+		return nil
+	})
+	sort.Strings(names)
+	var entries []fs.DirEntry
+	for i, n := range names {
+		if (i == 0 || names[i-1] != n) && fs.ValidPath(n) && !strings.Contains(n, "/") && !isInternal(n) {
+			entries = append(entries, fsInfo{name: n, dir: true})
+		}
+	}
+	return &fsDir{info: fsInfo{name: ".", dir: true}, entries: entries}
+}
+
+// dirEntries lists the files and directories directly below prefix, the
+// overlaid records taking the place of those in bucket. bucket may be nil.
+func dirEntries(bucket *bolt.Bucket, overlaid map[string][]byte, prefix string) []fs.DirEntry {
+	var entries []fs.DirEntry
+	dirs := make(map[string]bool)
+	add := func(k string, v []byte) {
+		rest := k[len(prefix):]
+		if !fs.ValidPath(rest) {
+			return
+		}
+		if dir, _, ok := strings.Cut(rest, "/"); ok {
+			if !dirs[dir] {
+				dirs[dir] = true
+				entries = append(entries, fsInfo{name: dir, dir: true})
+			}
+			return
+		}
+		var value valueT
+		if err := value.UnmarshalBinary(v); err != nil || value.isExpired() {
+			return
+		}
+		entries = append(entries, fsInfo{name: rest, size: int64(len(value.Value))})
+	}
+	if bucket != nil {
+		c := bucket.Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if _, ok := overlaid[string(k)]; !ok {
+				add(string(k), v)
+			}
+		}
+	}
+	for k, v := range overlaid {
+		if v != nil && strings.HasPrefix(k, prefix) {
+			add(k, v)
+		}
+	}
+	// a directory "a" sorts after a file "a.txt" in key order
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+// hasLive reports whether any overlaid record is not a deletion
+func hasLive(overlaid map[string][]byte) bool {
+	for _, buf := range overlaid {
+		if buf != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func pathBase(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// fsInfo implements both fs.FileInfo and fs.DirEntry
+type fsInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i fsInfo) Name() string               { return i.name }
+func (i fsInfo) Size() int64                { return i.size }
+func (i fsInfo) ModTime() time.Time         { return time.Time{} }
+func (i fsInfo) IsDir() bool                { return i.dir }
+func (i fsInfo) Sys() any                   { return nil }
+func (i fsInfo) Info() (fs.FileInfo, error) { return i, nil }
+func (i fsInfo) Type() fs.FileMode          { return i.Mode().Type() }
+
+func (i fsInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type fsFile struct {
+	info fsInfo
+	*bytes.Reader
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+type fsDir struct {
+	info    fsInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
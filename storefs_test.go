@@ -0,0 +1,119 @@
+package gostore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	records := map[string]string{
+		"index.html":       "<html></html>",
+		"css/site.css":     "body {}",
+		"css/print/a.css":  "a {}",
+		"templates/x.tmpl": "{{.}}",
+	}
+	for key, value := range records {
+		if err := s.Put("assets", []byte(key), []byte(value)); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := s.PutIdempotent("other", []byte("key"), []byte("value"), "token"); err != nil {
+		t.Error(err)
+	}
+
+	fsys := s.FS()
+	if err := fstest.TestFS(fsys, "assets/index.html", "assets/css/site.css",
+		"assets/css/print/a.css", "assets/templates/x.tmpl", "other/key"); err != nil {
+		t.Error(err)
+	}
+	data, err := fs.ReadFile(fsys, "assets/css/site.css")
+	if err != nil {
+		t.Error(err)
+	}
+	if string(data) != "body {}" {
+		t.Errorf("expected value %s, got %s", "body {}", data)
+	}
+	if _, err := fs.Stat(fsys, _idempotencyBucket); err == nil {
+		t.Error("expected internal bucket to be hidden")
+	}
+}
+
+func TestFSEphemeralWrites(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("assets", []byte("a.css"), []byte("a")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("assets", []byte("b.css"), []byte("b")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(path, WithReadOnly(), WithEphemeralWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("assets", []byte("a.css"), []byte("overlaid")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete("assets", []byte("b.css")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("drafts", []byte("js/c.js"), []byte("c")); err != nil {
+		t.Error(err)
+	}
+	fsys := s.FS()
+	if err := fstest.TestFS(fsys, "assets/a.css", "drafts/js/c.js"); err != nil {
+		t.Error(err)
+	}
+	if data, err := fs.ReadFile(fsys, "assets/a.css"); err != nil || string(data) != "overlaid" {
+		t.Errorf("expected value %s, got %s, %v", "overlaid", data, err)
+	}
+	if _, err := fs.Stat(fsys, "assets/b.css"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected error %s, got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestFSHashedKeys(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithHashedKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("assets", []byte("a.css"), []byte("a")); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.ReadFile(s.FS(), "assets/a.css"); !errors.Is(err, ErrHashedKeys) {
+		t.Errorf("expected error %s, got %v", ErrHashedKeys, err)
+	}
+}
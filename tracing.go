@@ -0,0 +1,48 @@
+package gostore
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const _tracerName = "github.com/millken/gostore"
+
+// WithTracerProvider records a span for every Get, Put, Delete and Memoize
+// call, with the namespace and the key length as attributes. Puts include
+// the writes of Update and Memoize. The spans of GetContext, PutContext,
+// DeleteContext and MemoizeContext join the trace of their context; the
+// other calls take none, so their spans are roots of their own traces.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *option) error {
+		o.tracer = tp.Tracer(_tracerName)
+		return nil
+	}
+}
+
+func endNothing(error) {}
+
+// trace starts a span named op as a child of the span of ctx. It returns
+// the context of the new span and the function ending it with the error of
+// the call.
+func (s *Store) trace(ctx context.Context, op string, namespace, key []byte) (context.Context, func(err error)) {
+	tracer := s.opt.Load().tracer
+	if tracer == nil {
+		return ctx, endNothing
+	}
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(
+		attribute.String("gostore.namespace", string(namespace)),
+		attribute.Int("gostore.key_length", len(key)),
+	))
+	return ctx, func(err error) {
+		// a missing key is an answer, not a failure
+		if err != nil && !errors.Is(err, ErrKeyNotFound) && !errors.Is(err, ErrKeyExpired) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,176 @@
+package gostore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  []attribute.KeyValue
+	status codes.Code
+}
+
+// recorder is a TracerProvider keeping the spans it ends
+type recorder struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []recordedSpan
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{r: r}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	r *recorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	span := &recordingSpan{r: t.r, span: recordedSpan{name: name, attrs: cfg.Attributes()}}
+	if parent, ok := trace.SpanFromContext(ctx).(*recordingSpan); ok {
+		span.span.parent = parent.span.name
+	}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	noop.Span
+	r    *recorder
+	span recordedSpan
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.span.status = code }
+
+func (s *recordingSpan) End(...trace.SpanEndOption) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.spans = append(s.r.spans, s.span)
+}
+
+func TestTracerProvider(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	tp := &recorder{}
+	s, err := Open(path, WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("ns", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get([]byte("ns"), []byte("missing")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	if err := s.Delete("ns", []byte("key")); err != nil {
+		t.Error(err)
+	}
+	var obj jsonValue
+	if err := s.Memoize("memo", &obj, func() (any, error) {
+		return jsonValue{"a": 1}, nil
+	}); err != nil {
+		t.Error(err)
+	}
+
+	expected := []string{"gostore.Put", "gostore.Get", "gostore.Delete", "gostore.Put", "gostore.Memoize"}
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if len(tp.spans) != len(expected) {
+		t.Fatalf("expected %d spans got %v", len(expected), tp.spans)
+	}
+	for i, span := range tp.spans {
+		if span.name != expected[i] {
+			t.Errorf("expected span %s got %s", expected[i], span.name)
+		}
+		if span.status == codes.Error {
+			t.Errorf("expected %s to succeed", span.name)
+		}
+	}
+	attrs := attribute.NewSet(tp.spans[0].attrs...)
+	if v, _ := attrs.Value("gostore.namespace"); v.AsString() != "ns" {
+		t.Errorf("expected namespace ns got %s", v.AsString())
+	}
+	if v, _ := attrs.Value("gostore.key_length"); v.AsInt64() != 3 {
+		t.Errorf("expected key length 3 got %d", v.AsInt64())
+	}
+}
+
+func TestTracerProviderContext(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	tp := &recorder{}
+	s, err := Open(path, WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, request := tp.Tracer("test").Start(context.Background(), "request")
+	if err := s.PutContext(ctx, []byte("ns"), []byte("key"), []byte("value"), 0); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.GetContext(ctx, []byte("ns"), []byte("key")); err != nil {
+		t.Error(err)
+	}
+	if err := s.DeleteContext(ctx, "ns", []byte("key")); err != nil {
+		t.Error(err)
+	}
+	var obj jsonValue
+	if err := s.MemoizeContext(ctx, "memo", &obj, func() (any, error) {
+		return jsonValue{"a": 1}, nil
+	}, 0); err != nil {
+		t.Error(err)
+	}
+	request.End()
+
+	expected := []recordedSpan{
+		{name: "gostore.Put", parent: "request"},
+		{name: "gostore.Get", parent: "request"},
+		{name: "gostore.Delete", parent: "request"},
+		{name: "gostore.Put", parent: "gostore.Memoize"},
+		{name: "gostore.Memoize", parent: "request"},
+		{name: "request"},
+	}
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if len(tp.spans) != len(expected) {
+		t.Fatalf("expected %d spans got %v", len(expected), tp.spans)
+	}
+	for i, span := range tp.spans {
+		if span.name != expected[i].name || span.parent != expected[i].parent {
+			t.Errorf("expected span %s under %q got %s under %q", expected[i].name, expected[i].parent, span.name, span.parent)
+		}
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.GetContext(canceled, []byte("ns"), []byte("key")); err != context.Canceled {
+		t.Errorf("expected error %s, got %v", context.Canceled, err)
+	}
+}
+
+// jsonValue is a map stored as JSON
+type jsonValue map[string]int
+
+func (v jsonValue) MarshalBinary() ([]byte, error) { return json.Marshal(v) }
+
+func (v *jsonValue) UnmarshalBinary(b []byte) error { return json.Unmarshal(b, v) }
@@ -0,0 +1,95 @@
+package gostore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Touch sets the expiry of key to ttl seconds from now, zero for none,
+// keeping its value. It returns ErrKeyExpired if the key already expired.
+func (s *Store) Touch(namespace, key []byte, ttl int64) error {
+	dk := s.diskKey(key)
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		if bucket == nil {
+			return ErrKeyNotFound
+		}
+		v := bucket.Get(dk)
+		if v == nil {
+			return ErrKeyNotFound
+		}
+		expire, ok := expireOf(v)
+		if !ok {
+			return ErrCorrupted
+		}
+		if !expire.IsZero() && time.Now().After(expire) {
+			return ErrKeyExpired
+		}
+		// only the expiry at the end of the record changes
+		buf := append([]byte(nil), v...)
+		binary.LittleEndian.PutUint64(buf[len(buf)-8:], uint64(newValueT(nil, ttl).Expire.Unix()))
+		return s.put(tx, namespace, dk, buf)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to touch key %s: %w", key, err)
+	}
+	return err
+}
+
+// GetTTL returns the value of key with the time left until it expires, zero
+// if it never does.
+func (s *Store) GetTTL(namespace, key []byte) (value []byte, remaining time.Duration, err error) {
+	key = s.diskKey(key)
+	valT, err := s.get(namespace, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if valT.Expire.IsZero() {
+		return valT.Value, 0, nil
+	}
+	remaining = time.Until(valT.Expire)
+	if remaining <= 0 {
+		s.notifyExpired(namespace, key, valT.Expire)
+		return nil, 0, ErrKeyExpired
+	}
+	return valT.Value, remaining, nil
+}
+
+// TTLMulti returns the time left until each of keys expires in a single
+// read transaction, in the order of keys. Keys that never expire have zero,
+// missing and expired keys a negative duration.
+func (s *Store) TTLMulti(namespace []byte, keys ...[]byte) ([]time.Duration, error) {
+	ttls := make([]time.Duration, len(keys))
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(namespace)
+		now := time.Now()
+		for i, key := range s.diskKeys(keys) {
+			ttls[i] = -1
+			v, ok := s.overlay.get(namespace, key)
+			if !ok && bucket != nil {
+				v = bucket.Get(key)
+			}
+			if v == nil {
+				continue
+			}
+			expire, ok := expireOf(v)
+			if !ok {
+				return fmt.Errorf("key %s: %w", key, ErrCorrupted)
+			}
+			switch {
+			case expire.IsZero():
+				ttls[i] = 0
+			case expire.After(now):
+				ttls[i] = expire.Sub(now)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ttls, nil
+}
@@ -0,0 +1,124 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTouch(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("sessions")
+	if err := s.PutWithTTL(ns, []byte("a"), []byte("value"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL(ns, []byte("b"), []byte("value"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.Touch(ns, []byte("a"), 10); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+	if v, err := s.Get(ns, []byte("a")); err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+	if err := s.Touch(ns, []byte("b"), 10); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("expected error %s, got %v", ErrKeyExpired, err)
+	}
+	if err := s.Touch(ns, []byte("missing"), 10); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	if err := s.Touch(ns, []byte("a"), 0); err != nil {
+		t.Error(err)
+	}
+	if value, err := s.get(ns, []byte("a")); err != nil || !value.Expire.IsZero() {
+		t.Errorf("expected no expiry, got %v, %v", value.Expire, err)
+	}
+}
+
+func TestGetTTL(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("sessions")
+	if err := s.PutWithTTL(ns, []byte("a"), []byte("value"), 10); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("b"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	v, remaining, err := s.GetTTL(ns, []byte("a"))
+	if err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+	if remaining <= 8*time.Second || remaining > 10*time.Second {
+		t.Errorf("expected about 10s got %s", remaining)
+	}
+	if _, remaining, err := s.GetTTL(ns, []byte("b")); err != nil || remaining != 0 {
+		t.Errorf("expected no expiry got %s, %v", remaining, err)
+	}
+	if _, _, err := s.GetTTL(ns, []byte("missing")); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestTTLMulti(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("sessions")
+	if err := s.PutWithTTL(ns, []byte("a"), []byte("v"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL(ns, []byte("b"), []byte("v"), 100); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("c"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+
+	ttls, err := s.TTLMulti(ns, []byte("a"), []byte("b"), []byte("c"), []byte("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ttls) != 4 {
+		t.Fatalf("expected 4 ttls got %d", len(ttls))
+	}
+	if ttls[0] >= 0 || ttls[3] >= 0 {
+		t.Errorf("expected negative ttls for expired and missing keys got %s, %s", ttls[0], ttls[3])
+	}
+	if ttls[1] <= 90*time.Second || ttls[1] > 100*time.Second {
+		t.Errorf("expected about 98s got %s", ttls[1])
+	}
+	if ttls[2] != 0 {
+		t.Errorf("expected 0 got %s", ttls[2])
+	}
+}
@@ -0,0 +1,77 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// StoreTx reads and writes records within a Store.Tx transaction.
+type StoreTx struct {
+	s  *Store
+	tx *bolt.Tx
+}
+
+// Tx runs fn in a single write transaction: either all its writes are
+// committed or, if fn returns an error, none is. fn may run more than once
+// when the commit is retried, so it should not have other side effects.
+// There are no savepoints: a write also updates views, the change log,
+// quotas and the dual-write queue and registers commit callbacks, none of
+// which can be taken back short of rolling back the whole transaction.
+func (s *Store) Tx(fn func(tx *StoreTx) error) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		if err := fn(&StoreTx{s: s, tx: tx}); err != nil {
+			return permanentError{err}
+		}
+		return nil
+	})
+	var p permanentError
+	if errors.As(err, &p) {
+		return p.err
+	}
+	return err
+}
+
+// Get fetches a value by key, seeing the writes made earlier in the
+// transaction
+func (t *StoreTx) Get(namespace, key []byte) ([]byte, error) {
+	key = t.s.diskKey(key)
+	bucket := t.tx.Bucket(namespace)
+	if bucket == nil || bucket.Get(key) == nil {
+		return nil, ErrKeyNotFound
+	}
+	value, ok, err := t.s.current(t.tx, namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrKeyExpired
+	}
+	return value, nil
+}
+
+// Put inserts a <key, value> record
+func (t *StoreTx) Put(namespace, key, value []byte) error {
+	return t.PutWithTTL(namespace, key, value, 0)
+}
+
+// PutWithTTL inserts a <key, value> record with TTL
+func (t *StoreTx) PutWithTTL(namespace, key, value []byte, ttl int64) error {
+	if value == nil {
+		return ErrBadValue
+	}
+	buf, err := newValueT(value, ttl).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := t.s.put(t.tx, namespace, t.s.diskKey(key), buf); err != nil {
+		return fmt.Errorf("failed to put key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete deletes a record by key
+func (t *StoreTx) Delete(namespace, key []byte) error {
+	return t.s.delete(t.tx, namespace, t.s.diskKey(key))
+}
@@ -0,0 +1,67 @@
+package gostore
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	objects, index := []byte("objects"), []byte("index")
+	if err := s.Tx(func(tx *StoreTx) error {
+		if err := tx.Put(objects, []byte("1"), []byte("alice")); err != nil {
+			return err
+		}
+		if err := tx.PutWithTTL(index, []byte("alice"), []byte("1"), 3600); err != nil {
+			return err
+		}
+		v, err := tx.Get(objects, []byte("1"))
+		if err != nil || string(v) != "alice" {
+			t.Errorf("expected alice got %s, %v", v, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(index, []byte("alice")); err != nil || string(v) != "1" {
+		t.Errorf("expected 1 got %s, %v", v, err)
+	}
+
+	errAbort := errors.New("abort")
+	runs := 0
+	if err := s.Tx(func(tx *StoreTx) error {
+		runs++
+		if err := tx.Delete(objects, []byte("1")); err != nil {
+			return err
+		}
+		if _, err := tx.Get(objects, []byte("1")); err != ErrKeyNotFound {
+			t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+		}
+		if err := tx.Put(objects, []byte("2"), []byte("bob")); err != nil {
+			return err
+		}
+		return errAbort
+	}); err != errAbort {
+		t.Errorf("expected error %s, got %v", errAbort, err)
+	}
+	if runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+	if _, err := s.Get(objects, []byte("1")); err != nil {
+		t.Errorf("expected rolled back delete, got %v", err)
+	}
+	if _, err := s.Get(objects, []byte("2")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
@@ -0,0 +1,89 @@
+package gostore
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+)
+
+// Key is the key type of a TypedNamespace. Strings and byte slices are
+// stored as they are, integers as 8 big-endian bytes that sort
+// numerically.
+type Key interface {
+	~string | ~[]byte | ~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64
+}
+
+// TypedNamespace reads and writes the records of one namespace as values
+// of type V under keys of type K, encoding values with the store codec.
+// Declaring namespaces once, for example as package variables, keeps their
+// names and types in one place:
+//
+//	var Users = gostore.NewTypedNamespace[UserID, User](store, "users", 0)
+//
+//	user, err := Users.Get(ctx, id)
+type TypedNamespace[K Key, V any] struct {
+	s    *Store
+	name []byte
+	ttl  int64
+}
+
+// NewTypedNamespace returns typed accessors of namespace name. Records put
+// through it expire after ttl seconds, zero for never.
+func NewTypedNamespace[K Key, V any](s *Store, name string, ttl int64) *TypedNamespace[K, V] {
+	return &TypedNamespace[K, V]{s: s, name: []byte(name), ttl: ttl}
+}
+
+// Name returns the name of the namespace
+func (n *TypedNamespace[K, V]) Name() string {
+	return string(n.name)
+}
+
+// Get returns the value of key. A pointer type V gets a newly allocated
+// value.
+func (n *TypedNamespace[K, V]) Get(ctx context.Context, key K) (V, error) {
+	var v V
+	data, err := n.s.GetContext(ctx, n.name, encodeKey(key))
+	if err != nil {
+		return v, err
+	}
+	var target any = &v
+	if rt := reflect.TypeOf(v); rt != nil && rt.Kind() == reflect.Pointer {
+		v = reflect.New(rt.Elem()).Interface().(V)
+		target = v
+	}
+	err = n.s.codec().Unmarshal(data, target)
+	return v, err
+}
+
+// Put sets the value of key
+func (n *TypedNamespace[K, V]) Put(ctx context.Context, key K, v V) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := n.s.codec().Marshal(v)
+	if err != nil {
+		return err
+	}
+	return n.s.PutContext(ctx, n.name, encodeKey(key), data, n.ttl)
+}
+
+// Delete deletes key
+func (n *TypedNamespace[K, V]) Delete(ctx context.Context, key K) error {
+	return n.s.DeleteContext(ctx, string(n.name), encodeKey(key))
+}
+
+// encodeKey returns the stored form of a key
+func encodeKey[K Key](key K) []byte {
+	rv := reflect.ValueOf(key)
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String())
+	case reflect.Slice:
+		return rv.Bytes()
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		// flipping the sign bit sorts negative numbers first
+		return binary.BigEndian.AppendUint64(nil, uint64(rv.Int())^1<<63)
+	default:
+		return binary.BigEndian.AppendUint64(nil, rv.Uint())
+	}
+}
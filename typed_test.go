@@ -0,0 +1,64 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+type userID int64
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestTypedNamespace(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	users := NewTypedNamespace[userID, user](s, "users", 0)
+	if err := users.Put(ctx, 42, user{Name: "ann"}); err != nil {
+		t.Error(err)
+	}
+	u, err := users.Get(ctx, 42)
+	if err != nil || u.Name != "ann" {
+		t.Errorf("expected ann got %+v, %v", u, err)
+	}
+	if _, err := users.Get(ctx, 7); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+	pointers := NewTypedNamespace[string, *user](s, "users", 0)
+	if err := pointers.Put(ctx, "ann", &user{Name: "ann"}); err != nil {
+		t.Error(err)
+	}
+	if p, err := pointers.Get(ctx, "ann"); err != nil || p == nil || p.Name != "ann" {
+		t.Errorf("expected ann got %+v, %v", p, err)
+	}
+	if err := users.Delete(ctx, 42); err != nil {
+		t.Error(err)
+	}
+	if _, err := users.Get(ctx, 42); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := users.Put(cancelled, 1, user{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %s, got %v", context.Canceled, err)
+	}
+
+	if bytes.Compare(encodeKey(-1), encodeKey(1)) >= 0 || bytes.Compare(encodeKey(uint32(2)), encodeKey(uint32(256))) >= 0 {
+		t.Error("expected integer keys to sort numerically")
+	}
+}
@@ -0,0 +1,208 @@
+package gostore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrBadView is returned by CreateView for a view that would read from or
+// write to itself, another view or an internal bucket.
+var ErrBadView = errors.New("bad view")
+
+// ErrViewReadOnly is returned for a write to a view, which only changes
+// with its source.
+var ErrViewReadOnly = errors.New("view is read-only")
+
+// views holds the registered views by source namespace
+type views struct {
+	mu       sync.RWMutex
+	bySource map[string][]*view
+}
+
+// view is a namespace holding the records of its source that pass filter,
+// with the values returned by project
+type view struct {
+	name    []byte
+	source  string
+	filter  func(key, value []byte) bool
+	project func(key, value []byte) []byte
+}
+
+// CreateView makes namespace name a view of source: it holds the live
+// records of source for which filter returns true, with the values returned
+// by project and the same expiry. A nil filter keeps every record, a nil
+// project the values. The view is built from source in one transaction and
+// then kept up to date in the transaction of every write to source. It is
+// read like any other namespace; writes to it fail with ErrViewReadOnly.
+// Views are not persisted, so they are created again after each Open;
+// creating one that exists rebuilds it.
+func (s *Store) CreateView(name, source string, filter func(key, value []byte) bool, project func(key, value []byte) []byte) error {
+	if name == source || isInternal(name) || isInternal(source) || s.isView(source) || s.hasViews(name) {
+		return fmt.Errorf("%w: %s of %s", ErrBadView, name, source)
+	}
+	v := &view{name: []byte(name), source: source, filter: filter, project: project}
+	prev := s.lookupView(name)
+	err := s.update(func(tx *bolt.Tx) error {
+		// registered while tx holds the writer lock, so every write to
+		// source committed after it updates the view
+		s.addView(v)
+		if err := tx.DeleteBucket(v.name); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		dst, err := tx.CreateBucket(v.name)
+		if err != nil {
+			return err
+		}
+		if src := tx.Bucket([]byte(source)); src != nil {
+			now := time.Now()
+			if err := src.ForEach(func(k, buf []byte) error {
+				var value valueT
+				if value.UnmarshalBinary(buf) != nil || (!value.Expire.IsZero() && now.After(value.Expire)) {
+					return nil
+				}
+				out, ok := v.apply(k, value)
+				if !ok {
+					return nil
+				}
+				return dst.Put(k, out)
+			}); err != nil {
+				return err
+			}
+		}
+		tx.OnCommit(func() { s.cachePurge(v.name) })
+		return nil
+	})
+	if err != nil {
+		if prev != nil {
+			s.addView(prev)
+		} else {
+			s.removeView(name)
+		}
+		return fmt.Errorf("failed to create view %s: %w", name, err)
+	}
+	return nil
+}
+
+// DropView stops maintaining a view and deletes its namespace.
+func (s *Store) DropView(name string) error {
+	if !s.isView(name) {
+		return fmt.Errorf("%w: %s is not a view", ErrBadView, name)
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(name)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		tx.OnCommit(func() {
+			s.removeView(name)
+			s.cachePurge([]byte(name))
+		})
+		return nil
+	})
+}
+
+// apply returns the encoded view record of a source record, and false if
+// the view leaves it out
+func (v *view) apply(key []byte, value valueT) ([]byte, bool) {
+	if v.filter != nil && !v.filter(key, value.Value) {
+		return nil, false
+	}
+	if v.project != nil {
+		value.Value = v.project(key, value.Value)
+	}
+	buf, _ := value.MarshalBinary()
+	return buf, true
+}
+
+func (s *Store) addView(v *view) {
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+	s.removeViewLocked(string(v.name))
+	if s.views.bySource == nil {
+		s.views.bySource = make(map[string][]*view)
+	}
+	s.views.bySource[v.source] = append(s.views.bySource[v.source], v)
+}
+
+func (s *Store) removeView(name string) {
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+	s.removeViewLocked(name)
+}
+
+func (s *Store) removeViewLocked(name string) {
+	for source, vs := range s.views.bySource {
+		for i, v := range vs {
+			if string(v.name) == name {
+				s.views.bySource[source] = append(vs[:i:i], vs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (s *Store) isView(name string) bool {
+	return s.lookupView(name) != nil
+}
+
+// lookupView returns the view named name, nil if there is none
+func (s *Store) lookupView(name string) *view {
+	s.views.mu.RLock()
+	defer s.views.mu.RUnlock()
+	for _, vs := range s.views.bySource {
+		for _, v := range vs {
+			if string(v.name) == name {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// hasViews reports whether namespace is the source of any view
+func (s *Store) hasViews(namespace string) bool {
+	s.views.mu.RLock()
+	defer s.views.mu.RUnlock()
+	return len(s.views.bySource[namespace]) > 0
+}
+
+// updateViews applies a change of namespace to its views within tx
+func (s *Store) updateViews(tx *bolt.Tx, op ChangeOp, namespace, key, buf []byte) error {
+	s.views.mu.RLock()
+	vs := s.views.bySource[string(namespace)]
+	s.views.mu.RUnlock()
+	for _, v := range vs {
+		bucket, err := tx.CreateBucketIfNotExists(v.name)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case OpPut:
+			var value valueT
+			if err := value.UnmarshalBinary(buf); err != nil {
+				return err
+			}
+			if out, ok := v.apply(key, value); ok {
+				err = bucket.Put(key, out)
+			} else {
+				err = bucket.Delete(key)
+			}
+			s.invalidate(tx, v.name, key)
+		case OpDelete:
+			err = bucket.Delete(key)
+			s.invalidate(tx, v.name, key)
+		case OpDeleteNamespace:
+			if err = tx.DeleteBucket(v.name); err == nil {
+				_, err = tx.CreateBucket(v.name)
+			}
+			tx.OnCommit(func() { s.cachePurge(v.name) })
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package gostore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreateView(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	active := func(key, value []byte) bool { return bytes.HasSuffix(value, []byte(":active")) }
+	name := func(key, value []byte) []byte { return bytes.TrimSuffix(value, []byte(":active")) }
+	if err := s.Put("users", []byte("1"), []byte("ann:active")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("users", []byte("2"), []byte("bob:idle")); err != nil {
+		t.Error(err)
+	}
+	if err := s.CreateView("active_users", "users", active, name); err != nil {
+		t.Fatal(err)
+	}
+	view := []byte("active_users")
+	if v, err := s.Get(view, []byte("1")); err != nil || string(v) != "ann" {
+		t.Errorf("expected ann got %s, %v", v, err)
+	}
+	if _, err := s.Get(view, []byte("2")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+
+	// writes to the source keep the view up to date
+	if err := s.Put("users", []byte("2"), []byte("bob:active")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("users", []byte("1"), []byte("ann:idle")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("users", []byte("3"), []byte("cid:active")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete("users", []byte("3")); err != nil {
+		t.Error(err)
+	}
+	kvs, err := s.Scan(view, nil, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 1 || string(kvs[0].Key) != "2" || string(kvs[0].Value) != "bob" {
+		t.Errorf("expected only 2=bob got %v", kvs)
+	}
+
+	if err := s.DeleteNamespace("users"); err != nil {
+		t.Error(err)
+	}
+	if kvs, err := s.Scan(view, nil, 0); err != nil || len(kvs) != 0 {
+		t.Errorf("expected empty view got %v, %v", kvs, err)
+	}
+
+	if err := s.CreateView("nested", "active_users", nil, nil); !errors.Is(err, ErrBadView) {
+		t.Errorf("expected error %s, got %v", ErrBadView, err)
+	}
+	if err := s.DropView("active_users"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("users", []byte("4"), []byte("dan:active")); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(view, []byte("4")); err != ErrKeyNotFound {
+		t.Errorf("expected error %s, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestViewConcurrentWrites(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const n = 500
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			if err := s.Put("src", []byte(fmt.Sprint(i)), []byte("v")); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := s.CreateView("all", "src", nil, nil); err != nil {
+			t.Error(err)
+		}
+	}
+	<-done
+	for i := 0; i < n; i++ {
+		if _, err := s.Get([]byte("all"), []byte(fmt.Sprint(i))); err != nil {
+			t.Errorf("expected key %d in the view, got %v", i, err)
+		}
+	}
+
+	if err := s.Put("all", []byte("x"), []byte("v")); !errors.Is(err, ErrViewReadOnly) {
+		t.Errorf("expected error %s, got %v", ErrViewReadOnly, err)
+	}
+	if err := s.Delete("all", []byte("1")); !errors.Is(err, ErrViewReadOnly) {
+		t.Errorf("expected error %s, got %v", ErrViewReadOnly, err)
+	}
+	if err := s.DeleteNamespace("all"); !errors.Is(err, ErrViewReadOnly) {
+		t.Errorf("expected error %s, got %v", ErrViewReadOnly, err)
+	}
+}
+
+func TestCreateViewRollback(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	errCommit := errors.New("commit failed")
+	var fail atomic.Bool
+	s, err := Open(path, WithNumRetries(1), WithFailpoints(Failpoints{BeforeCommit: func() error {
+		if fail.Load() {
+			return errCommit
+		}
+		return nil
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fail.Store(true)
+	if err := s.CreateView("all", "src", nil, nil); !errors.Is(err, errCommit) {
+		t.Errorf("expected error %s, got %v", errCommit, err)
+	}
+	fail.Store(false)
+	if s.isView("all") {
+		t.Error("expected the view not to be registered")
+	}
+	if err := s.Put("all", []byte("x"), []byte("v")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestViewGC(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.PutWithTTL([]byte("src"), []byte("a"), []byte("1"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("src", []byte("b"), []byte("2")); err != nil {
+		t.Error(err)
+	}
+	if err := s.CreateView("all", "src", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Second)
+
+	if n, err := s.GC(); err != nil || n != 1 {
+		t.Errorf("expected 1 deleted got %d, %v", n, err)
+	}
+	kvs, err := s.Scan([]byte("all"), nil, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(kvs) != 1 || string(kvs[0].Key) != "b" {
+		t.Errorf("expected only b in the view got %v", kvs)
+	}
+}
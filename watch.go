@@ -0,0 +1,137 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const _watchQueueSize = 256
+
+// EventType is the kind of change reported by Watch.
+type EventType uint8
+
+const (
+	// EventPut is a record being written.
+	EventPut EventType = iota + 1
+	// EventDelete is a record being deleted.
+	EventDelete
+	// EventExpire is an expired record being deleted, usually by the
+	// garbage collector.
+	EventExpire
+	// EventDeleteNamespace is the whole namespace being deleted. Key is
+	// empty.
+	EventDeleteNamespace
+)
+
+// Event is a committed change reported by Watch.
+type Event struct {
+	Type      EventType
+	Namespace []byte
+	Key       []byte
+	// Value is set for EventPut.
+	Value []byte
+	// Expire is set for EventPut and EventExpire.
+	Expire time.Time
+}
+
+type watchers struct {
+	mu     sync.RWMutex
+	all    map[*watcher]struct{}
+	closed bool
+}
+
+type watcher struct {
+	namespace []byte
+	prefix    []byte
+	ch        chan Event
+}
+
+// Watch returns a channel receiving the changes to the keys of a namespace
+// starting with keyPrefix, once they commit, and a function to stop
+// watching. Deleting the namespace is reported whatever the prefix.
+// Delivery is best-effort: events are dropped while the channel is full.
+// Expired records are reported when they are deleted, so EventExpire needs
+// the garbage collector running. The channel is closed by cancel or Close.
+func (s *Store) Watch(namespace, keyPrefix []byte) (<-chan Event, context.CancelFunc) {
+	w := &watcher{
+		namespace: bytes.Clone(namespace),
+		prefix:    bytes.Clone(keyPrefix),
+		ch:        make(chan Event, _watchQueueSize),
+	}
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+	if s.watchers.closed {
+		close(w.ch)
+		return w.ch, func() {}
+	}
+	if s.watchers.all == nil {
+		s.watchers.all = make(map[*watcher]struct{})
+	}
+	s.watchers.all[w] = struct{}{}
+	return w.ch, func() {
+		s.watchers.mu.Lock()
+		defer s.watchers.mu.Unlock()
+		if _, ok := s.watchers.all[w]; ok {
+			delete(s.watchers.all, w)
+			close(w.ch)
+		}
+	}
+}
+
+// watched hands a change to the watchers once tx commits
+func (s *Store) watched(tx *bolt.Tx, op ChangeOp, namespace, key, buf []byte) error {
+	s.watchers.mu.RLock()
+	n := len(s.watchers.all)
+	s.watchers.mu.RUnlock()
+	if n == 0 {
+		return nil
+	}
+	ev := Event{Namespace: bytes.Clone(namespace), Key: bytes.Clone(key)}
+	switch op {
+	case OpPut:
+		var value valueT
+		if err := value.UnmarshalBinary(buf); err != nil {
+			return err
+		}
+		ev.Type, ev.Value, ev.Expire = EventPut, value.Value, value.Expire
+	case OpDelete:
+		ev.Type = EventDelete
+		if expire, ok := expireOf(buf); ok && !expire.IsZero() && time.Now().After(expire) {
+			ev.Type, ev.Expire = EventExpire, expire
+		}
+	case OpDeleteNamespace:
+		ev.Type = EventDeleteNamespace
+	}
+	tx.OnCommit(func() { s.notifyWatchers(ev) })
+	return nil
+}
+
+func (s *Store) notifyWatchers(ev Event) {
+	s.watchers.mu.RLock()
+	defer s.watchers.mu.RUnlock()
+	for w := range s.watchers.all {
+		if !bytes.Equal(w.namespace, ev.Namespace) {
+			continue
+		}
+		if ev.Type != EventDeleteNamespace && !bytes.HasPrefix(ev.Key, w.prefix) {
+			continue
+		}
+		select {
+		case w.ch <- ev:
+		default:
+		}
+	}
+}
+
+func (s *Store) closeWatchers() {
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+	for w := range s.watchers.all {
+		close(w.ch)
+	}
+	s.watchers.all, s.watchers.closed = nil, true
+}
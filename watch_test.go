@@ -0,0 +1,87 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := []byte("ns")
+	events, cancel := s.Watch(ns, []byte("user:"))
+	defer cancel()
+	all, _ := s.Watch(ns, nil)
+
+	if err := s.Put(string(ns), []byte("user:1"), []byte("ann")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put(string(ns), []byte("group:1"), []byte("admins")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("other", []byte("user:1"), []byte("ann")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete(string(ns), []byte("user:1")); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWithTTL(ns, []byte("user:2"), []byte("bob"), 1); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err := s.GC(); err != nil {
+		t.Error(err)
+	}
+	if err := s.DeleteNamespace(string(ns)); err != nil {
+		t.Error(err)
+	}
+
+	expected := []struct {
+		typ      EventType
+		key, val string
+	}{
+		{EventPut, "user:1", "ann"},
+		{EventDelete, "user:1", ""},
+		{EventPut, "user:2", "bob"},
+		{EventExpire, "user:2", ""},
+		{EventDeleteNamespace, "", ""},
+	}
+	for _, e := range expected {
+		select {
+		case ev := <-events:
+			if ev.Type != e.typ || string(ev.Key) != e.key || string(ev.Value) != e.val {
+				t.Errorf("expected %v %s=%s got %v %s=%s", e.typ, e.key, e.val, ev.Type, ev.Key, ev.Value)
+			}
+		default:
+			t.Fatalf("expected event %v %s", e.typ, e.key)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %v %s", ev.Type, ev.Key)
+	default:
+	}
+	if len(all) != 6 {
+		t.Errorf("expected 6 events without prefix got %d", len(all))
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("expected cancel to close the channel")
+	}
+	s.Close()
+	for range all {
+	}
+	if _, ok := <-all; ok {
+		t.Error("expected Close to close the channel")
+	}
+}
@@ -0,0 +1,13 @@
+package gostore
+
+// WithLargeValueCache keeps values of at least size bytes out of the LRU
+// cache. They go to a second-chance cache instead, whose entries are
+// dropped by the garbage collector once nothing else references them. The
+// second-chance cache needs Go 1.24; older toolchains do not cache large
+// values at all.
+func WithLargeValueCache(size int) Option {
+	return func(o *option) error {
+		o.largeValueSize = size
+		return nil
+	}
+}
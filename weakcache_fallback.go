@@ -0,0 +1,26 @@
+//go:build !go1.24
+
+package gostore
+
+import "time"
+
+// weakCache needs weak pointers from Go 1.24; without them nothing is cached.
+type weakCache struct{}
+
+func newWeakCache() *weakCache {
+	return &weakCache{}
+}
+
+// Add adds a value to the cache.
+func (c *weakCache) Add(key string, expire time.Time, value []byte) {}
+
+// Get looks up a key's value from the cache.
+func (c *weakCache) Get(key string) ([]byte, bool) {
+	return nil, false
+}
+
+// Delete deletes a key from the cache.
+func (c *weakCache) Delete(key string) {}
+
+// DeleteFunc deletes the keys for which match returns true.
+func (c *weakCache) DeleteFunc(match func(key string) bool) {}
@@ -0,0 +1,95 @@
+//go:build go1.24
+
+package gostore
+
+import (
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+	"weak"
+)
+
+// weakCache holds values through weak pointers to their first byte, so it
+// never keeps them alive but keeps them for as long as a slice of the same
+// array is.
+type weakCache struct {
+	mu    sync.Mutex
+	items map[string]weakEntry
+}
+
+type weakEntry struct {
+	expire time.Time
+	value  weak.Pointer[byte]
+	len    int
+}
+
+func newWeakCache() *weakCache {
+	return &weakCache{items: make(map[string]weakEntry)}
+}
+
+// Add adds a value to the cache. Empty values are not cached.
+func (c *weakCache) Add(key string, expire time.Time, value []byte) {
+	if len(value) == 0 {
+		return
+	}
+	p := &value[0]
+	wp := weak.Make(p)
+	c.mu.Lock()
+	c.items[key] = weakEntry{expire, wp, len(value)}
+	c.mu.Unlock()
+	runtime.AddCleanup(p, c.cleanup, weakKey{key, wp})
+}
+
+// Get looks up a key's value from the cache.
+func (c *weakCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ent, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if !ent.expire.IsZero() && !ent.expire.After(time.Now()) {
+		delete(c.items, key)
+		return nil, false
+	}
+	p := ent.value.Value()
+	if p == nil {
+		delete(c.items, key)
+		return nil, false
+	}
+	return unsafe.Slice(p, ent.len), true
+}
+
+// Delete deletes a key from the cache.
+func (c *weakCache) Delete(key string) {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+}
+
+// DeleteFunc deletes the keys for which match returns true.
+func (c *weakCache) DeleteFunc(match func(key string) bool) {
+	c.mu.Lock()
+	for key := range c.items {
+		if match(key) {
+			delete(c.items, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+type weakKey struct {
+	key   string
+	value weak.Pointer[byte]
+}
+
+// cleanup drops the map entry of a collected value, unless the key has been
+// added again since.
+func (c *weakCache) cleanup(k weakKey) {
+	c.mu.Lock()
+	if ent, ok := c.items[k.key]; ok && ent.value == k.value {
+		delete(c.items, k.key)
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,72 @@
+//go:build go1.24
+
+package gostore
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWeakCache(t *testing.T) {
+	c := newWeakCache()
+	c.Add("key", time.Time{}, []byte("value"))
+	c.Add("expired", time.Now().Add(-time.Second), []byte("value"))
+	if _, ok := c.Get("expired"); ok {
+		t.Error("expected expired to be dropped")
+	}
+	c.Delete("key")
+	if _, ok := c.Get("key"); ok {
+		t.Error("expected key to be deleted")
+	}
+
+	value := bytes.Repeat([]byte("x"), 64)
+	c.Add("key", time.Time{}, value)
+	runtime.GC()
+	if v, ok := c.Get("key"); !ok || !bytes.Equal(v, value) {
+		t.Errorf("expected the referenced value to stay cached, got %q, %v", v, ok)
+	}
+	runtime.KeepAlive(value)
+
+	c.Add("key", time.Time{}, bytes.Repeat([]byte("x"), 64))
+	runtime.GC()
+	if _, ok := c.Get("key"); ok {
+		t.Error("expected key to be collected")
+	}
+}
+
+func TestLargeValueCache(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithMaxCacheSize(10), WithLargeValueCache(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	large := &T1{Name: string(bytes.Repeat([]byte("x"), 64))}
+	if err := s.Update("large", large); err != nil {
+		t.Error(err)
+	}
+	if err := s.Update("small", &T1{Name: "small"}); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.lru.Get("large"); ok {
+		t.Error("expected large not to be in the LRU cache")
+	}
+	if _, ok := s.lru.Get("small"); !ok {
+		t.Error("expected small to be in the LRU cache")
+	}
+	var v T1
+	if err := s.Load("large", &v); err != nil {
+		t.Error(err)
+	}
+	if v.Name != large.Name {
+		t.Errorf("expected value %s, got %s", large.Name, v.Name)
+	}
+}
@@ -0,0 +1,161 @@
+package gostore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	_deadLetterBucket     = _internalPrefix + "webhook_dead"
+	_webhookQueueSize     = 1024
+	_webhookAttempts      = 3
+	_webhookRetryInterval = 100 * time.Millisecond
+	_webhookTimeout       = 10 * time.Second
+)
+
+// WebhookFilter selects the changes sent to a webhook. An empty namespace
+// matches all namespaces.
+type WebhookFilter struct {
+	Namespace string
+	Prefix    []byte
+}
+
+func (f WebhookFilter) match(change Change) bool {
+	if f.Namespace != "" && f.Namespace != string(change.Namespace) {
+		return false
+	}
+	return bytes.HasPrefix(change.Key, f.Prefix)
+}
+
+type webhookConfig struct {
+	url    string
+	filter WebhookFilter
+}
+
+// WithWebhook POSTs a JSON event for every put and delete matching filter.
+// Failed deliveries are retried and then kept in a dead-letter bucket, see
+// DeadLetters. It is disabled in read-only mode.
+func WithWebhook(url string, filter WebhookFilter) Option {
+	return func(o *option) error {
+		o.webhooks = append(o.webhooks, webhookConfig{url, filter})
+		return nil
+	}
+}
+
+// DeadLetters returns the webhook events that could not be delivered
+func (s *Store) DeadLetters() ([]Change, error) {
+	var changes []Change
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(_deadLetterBucket))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, v []byte) error {
+			var change Change
+			if err := json.Unmarshal(v, &change); err != nil {
+				return err
+			}
+			changes = append(changes, change)
+			return nil
+		})
+	})
+	return changes, err
+}
+
+type webhook struct {
+	s      *Store
+	filter WebhookFilter
+	sink   *WebhookSink
+	queue  chan Change
+}
+
+func (s *Store) startWebhook(cfg webhookConfig) {
+	w := &webhook{
+		s:      s,
+		filter: cfg.filter,
+		sink:   NewWebhookSink(cfg.url, nil),
+		queue:  make(chan Change, _webhookQueueSize),
+	}
+	s.listeners = append(s.listeners, w.enqueue)
+	s.wg.Add(1)
+	go w.run()
+}
+
+func (w *webhook) enqueue(change Change) {
+	if !w.filter.match(change) || change.Op == OpDeleteNamespace {
+		return
+	}
+	select {
+	case w.queue <- change:
+	default:
+		// the queue is full, don't stall the writer, which is still
+		// holding the database
+		w.s.wg.Add(1)
+		go func() {
+			defer w.s.wg.Done()
+			w.deadLetter(change)
+		}()
+	}
+}
+
+func (w *webhook) run() {
+	defer w.s.wg.Done()
+	for {
+		select {
+		case change := <-w.queue:
+			w.deliver(change)
+		case <-w.s.done:
+			// keep what is left for later inspection
+			for {
+				select {
+				case change := <-w.queue:
+					w.deadLetter(change)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *webhook) deliver(change Change) {
+	for attempt := 0; attempt < _webhookAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(_webhookRetryInterval << attempt):
+			case <-w.s.done:
+				w.deadLetter(change)
+				return
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), _webhookTimeout)
+		err := w.sink.Write(ctx, []Change{change})
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	w.deadLetter(change)
+}
+
+func (w *webhook) deadLetter(change Change) {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return
+	}
+	_ = w.s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(_deadLetterBucket))
+		if err != nil {
+			return err
+		}
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(seqKey(seq), body)
+	})
+}
@@ -0,0 +1,93 @@
+package gostore
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []Change
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var changes []Change
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, changes...)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithWebhook(srv.URL, WebhookFilter{Namespace: "users", Prefix: []byte("user:")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("users", []byte("user:1"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("users", []byte("group:1"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Put("other", []byte("user:1"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Delete("users", []byte("user:1")); err != nil {
+		t.Error(err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	})
+	if received[0].Op != OpPut || string(received[0].Value) != "value" {
+		t.Errorf("unexpected change %+v", received[0])
+	}
+	if received[1].Op != OpDelete || string(received[1].Key) != "user:1" {
+		t.Errorf("unexpected change %+v", received[1])
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path, WithWebhook(srv.URL, WebhookFilter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put("test", []byte("key"), []byte("value")); err != nil {
+		t.Error(err)
+	}
+	var dead []Change
+	waitFor(t, func() bool {
+		dead, err = s.DeadLetters()
+		return err == nil && len(dead) == 1
+	})
+	if string(dead[0].Key) != "key" {
+		t.Errorf("expected key %s, got %s", "key", dead[0].Key)
+	}
+}
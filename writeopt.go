@@ -0,0 +1,52 @@
+package gostore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WriteOption configures a single write made by PutWith.
+type WriteOption func(*writeOption)
+
+type writeOption struct {
+	ttl     int64
+	durable bool
+}
+
+// TTL makes the record expire ttl seconds after the write.
+func TTL(ttl int64) WriteOption {
+	return func(o *writeOption) {
+		o.ttl = ttl
+	}
+}
+
+// Durable syncs the database file to disk before the write returns, even
+// if the store does not sync as writes commit. Writes committed before it
+// are synced too.
+func Durable() WriteOption {
+	return func(o *writeOption) {
+		o.durable = true
+	}
+}
+
+// PutWith inserts a <key, value> record configured by opts
+func (s *Store) PutWith(namespace, key, value []byte, opts ...WriteOption) error {
+	var o writeOption
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var expire time.Time
+	if o.ttl != 0 {
+		expire = time.Now().Add(time.Duration(o.ttl) * time.Second)
+	}
+	if err := s.putWithExpire(context.Background(), namespace, key, value, expire); err != nil {
+		return err
+	}
+	if o.durable && s.overlay == nil && !s.opt.Load().sync {
+		if err := s.Sync(); err != nil {
+			return fmt.Errorf("failed to sync key %s: %w", key, err)
+		}
+	}
+	return nil
+}
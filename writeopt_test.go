@@ -0,0 +1,35 @@
+package gostore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPutWith(t *testing.T) {
+	path, err := tempfile()
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(path)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ns := []byte("payments")
+	if err := s.PutWith(ns, []byte("a"), []byte("value"), Durable()); err != nil {
+		t.Error(err)
+	}
+	if err := s.PutWith(ns, []byte("b"), []byte("value"), TTL(1), Durable()); err != nil {
+		t.Error(err)
+	}
+	if v, err := s.Get(ns, []byte("a")); err != nil || string(v) != "value" {
+		t.Errorf("expected value got %s, %v", v, err)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err := s.Get(ns, []byte("b")); err != ErrKeyExpired {
+		t.Errorf("expected error %s, got %v", ErrKeyExpired, err)
+	}
+}